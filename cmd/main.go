@@ -8,27 +8,60 @@
 // @in header
 // @name X-Api-Key
 
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT bearer token, "Bearer " followed by the token
+
 // @externalDocs.description  OpenAPI
 // @externalDocs.url          https://swagger.io/resources/open-api/
 
 package main
 
 import (
+	"context"
+	"cruder/internal/auth"
 	"cruder/internal/config"
 	"cruder/internal/controller"
 	"cruder/internal/handler"
+	"cruder/internal/logging"
+	"cruder/internal/metrics"
 	"cruder/internal/middleware"
 	"cruder/internal/repository"
+	"cruder/internal/server"
 	"cruder/internal/service"
+	"cruder/internal/tracing"
+	"fmt"
 	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel"
 )
 
 func main() {
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	// cruder config print writes the effective config to stdout, so logs go
+	// to stderr meanwhile.
+	printConfig := len(os.Args) > 1
+	logOut := os.Stdout
+	if printConfig {
+		if len(os.Args) != 3 || os.Args[1] != "config" || os.Args[2] != "print" {
+			fmt.Fprintln(os.Stderr, "usage: cruder [config print]")
+			os.Exit(2)
+		}
+		logOut = os.Stderr
+	}
+	logger := logging.New(logOut, slog.LevelInfo, "json")
 
 	if os.Getenv("APP_ENV") != "production" {
 		logger.Info("Running in development mode, loading .env file")
@@ -39,45 +72,180 @@ func main() {
 		}
 	}
 
-	apiKey := os.Getenv("X_API_KEY")
-	if apiKey == "" {
-		logger.Error("X_API_KEY environment variable is not set")
-		os.Exit(1)
-	}
-
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		logger.Error("failed to load config", slog.Any("err", err))
 		os.Exit(1)
 	}
+	if printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			logger.Error("failed to print config", slog.Any("err", err))
+			os.Exit(1)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		logger.Error("failed to validate config", slog.Any("err", err))
+		os.Exit(1)
+	}
+	if printConfig {
+		return
+	}
+	logger = logging.New(os.Stdout, cfg.LogLevel(), cfg.Logging.Format)
 
-	dsn, err := cfg.GetDSN()
+	apiKeys, err := cfg.GetAPIKeys()
 	if err != nil {
-		logger.Error("failed to get database connection string", slog.Any("err", err))
+		logger.Error("failed to load API keys", slog.Any("err", err))
 		os.Exit(1)
 	}
+	if len(apiKeys) == 0 {
+		logger.Warn("no API keys configured, only keys in the api_keys table are accepted")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
 
-	dbConn, err := repository.NewPostgresConnection(dsn)
+	tracerProvider, shutdownTracing, err := tracing.NewTracerProvider(ctx, tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		logger.Error("failed to set up tracing", slog.Any("err", err))
+		os.Exit(1)
+	}
+	otel.SetTracerProvider(tracerProvider)
+	otel.SetTextMapPropagator(tracing.Propagator)
+
+	dbConn, err := repository.NewPostgresConnection(ctx, cfg.GetDSN, repository.PoolOptions{
+		MaxOpenConns:    cfg.Database.Pool.MaxOpenConns,
+		MaxIdleConns:    cfg.Database.Pool.MaxIdleConns,
+		ConnMaxLifetime: cfg.Database.Pool.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.Database.Pool.ConnMaxIdleTime,
+	}, repository.RetryOptions{
+		Timeout:        cfg.Database.Connect.Timeout,
+		InitialBackoff: cfg.Database.Connect.InitialBackoff,
+		MaxBackoff:     cfg.Database.Connect.MaxBackoff,
+	}, tracerProvider, logger)
 	if err != nil {
 		logger.Error("failed to connect to database", slog.Any("err", err))
 		os.Exit(1)
 	}
 
-	repositories := repository.NewRepository(dbConn.DB())
-	services := service.NewService(repositories)
-	controllers := controller.NewController(services)
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(dbConn.DB(), "postgres"),
+	)
+	appMetrics := metrics.New(registry)
 
+	repositories := repository.NewRepository(dbConn.DB(), logger, appMetrics.QueryDuration)
+	services := service.NewService(repositories, cfg.Users.DeletedRetention, tracerProvider, controller.IsClientError)
+	controllers := controller.NewController(services, cfg.Users.LegacyIDRoutes, map[string]controller.Pinger{"database": dbConn.DB()}, logger)
+
+	publicRoutes := []string{"/healthz", "/readyz", "/version", "/metrics", "/swagger/*any"}
+	requestIDMiddleware := middleware.NewRequestIDMiddleware()
 	loggerMiddleware := middleware.NewLoggerMiddleWare(logger)
-	apiKeyMiddleware := middleware.NewApiKeyMiddleware(apiKey, []string{"/healthz", "/swagger/*any"})
+	metricsMiddleware := middleware.NewMetricsMiddleware(appMetrics)
+	staticKeys := auth.NewStaticKeyStore(apiKeys)
+	apiKeyMiddleware := middleware.NewApiKeyMiddleware(auth.NewMultiKeyStore(services.APIKeys, staticKeys), publicRoutes)
+	go func() {
+		// New database connections read the password themselves, only the
+		// API keys need reloading.
+		err := cfg.WatchSecrets(ctx, logger, func() {
+			keys, err := cfg.GetAPIKeys()
+			if err != nil {
+				logger.Warn("failed to reload API keys, keeping the previous ones", slog.Any("err", err))
+				return
+			}
+			staticKeys.Replace(keys)
+		})
+		if err != nil {
+			logger.Error("failed to watch secret files, secrets will not be reloaded", slog.Any("err", err))
+		}
+	}()
+
+	rateLimits := make(map[string]middleware.RateLimit, len(cfg.RateLimits))
+	for group, limit := range cfg.RateLimits {
+		rateLimits[group] = middleware.RateLimit{Rate: limit.Rate, Burst: limit.Burst}
+	}
+	rateLimiter := middleware.NewRateLimiter(rateLimits)
 
 	r := gin.New()
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		logger.Error("invalid trusted proxies", slog.Any("err", err))
+		os.Exit(1)
+	}
+	// Outside of the recovery, so that panics are counted as the 500s they
+	// are answered with.
+	r.Use(metricsMiddleware.Handler())
 	r.Use(gin.Recovery())
+	r.Use(otelgin.Middleware(tracing.ServiceName,
+		otelgin.WithTracerProvider(tracerProvider),
+		otelgin.WithPropagators(tracing.Propagator),
+		// Probes and scrapes would drown out the traces worth looking at.
+		otelgin.WithGinFilter(func(c *gin.Context) bool {
+			return c.FullPath() != "/healthz" && c.FullPath() != "/readyz" && c.FullPath() != "/metrics"
+		}),
+	))
+	r.Use(requestIDMiddleware.Handler())
 	r.Use(loggerMiddleware.Handler())
+	r.Use(rateLimiter.ClientIP(publicRoutes))
+	if jwtCfg := cfg.Auth.JWT; jwtCfg.JWKSFile != "" {
+		jwks, err := auth.LoadJWKS(jwtCfg.JWKSFile)
+		if err != nil {
+			logger.Error("failed to load JWKS", slog.Any("err", err))
+			os.Exit(1)
+		}
+		go func() {
+			if err := jwks.Watch(ctx, logger); err != nil {
+				logger.Error("failed to watch JWKS, keys will not be reloaded", slog.Any("err", err))
+			}
+		}()
+		jwtAuth := middleware.NewJWTAuth(jwks.Keyfunc, middleware.JWTOptions{
+			Issuer:     jwtCfg.Issuer,
+			Audience:   jwtCfg.Audience,
+			ScopeClaim: jwtCfg.ScopeClaim,
+		}, publicRoutes)
+		r.Use(jwtAuth.Handler())
+	}
 	r.Use(apiKeyMiddleware.Handler())
-	_ = r.SetTrustedProxies(nil)
 
-	handler.New(r, controllers.Users, controllers.Health)
-	if err := r.Run(); err != nil {
-		logger.Error("failed to run server", slog.Any("err", err))
+	handler.New(r, controllers.Users, controllers.Audit, controllers.APIKeys, controllers.Health, rateLimiter, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}), cfg.Users.LegacyIDRoutes)
+
+	ln, err := net.Listen("tcp", cfg.Server.Addr)
+	if err != nil {
+		logger.Error("failed to listen", slog.String("addr", cfg.Server.Addr), slog.Any("err", err))
+		os.Exit(1)
+	}
+	srv := &http.Server{
+		Handler:           r,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelError),
+	}
+	logger.Info("listening", slog.String("addr", ln.Addr().String()))
+	serveErr := server.Serve(ctx, srv, ln, controllers.Health.Drain, server.Options{
+		DrainDelay:      cfg.Server.DrainDelay,
+		ShutdownTimeout: cfg.Server.ShutdownTimeout,
+	}, logger)
+	if serveErr != nil {
+		logger.Error("failed to run server", slog.Any("err", serveErr))
+	}
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.Error("failed to flush spans", slog.Any("err", err))
+	}
+	cancel()
+	// Every request has finished, so nothing uses the database any more.
+	if err := dbConn.DB().Close(); err != nil {
+		logger.Error("failed to close database", slog.Any("err", err))
+	}
+	if serveErr != nil {
+		os.Exit(1)
 	}
+	logger.Info("shut down")
 }
@@ -0,0 +1,24 @@
+// Package auth carries the identity a request was authenticated as through
+// its context.
+package auth
+
+import "context"
+
+// UnknownActor is reported for contexts that carry no actor, such as
+// background jobs.
+const UnknownActor = "unknown"
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx that carries actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns the actor carried by ctx, or UnknownActor.
+func Actor(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return UnknownActor
+}
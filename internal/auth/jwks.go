@@ -0,0 +1,211 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWKS holds the keys bearer tokens are verified with, read from a JSON Web
+// Key Set file. It is safe for concurrent use.
+type JWKS struct {
+	path string
+
+	mu   sync.RWMutex
+	keys map[string]jwk
+}
+
+// jwk is a parsed verification key along with the only algorithm it may be
+// used with.
+type jwk struct {
+	alg string
+	key any
+}
+
+// LoadJWKS reads the key set in path.
+func LoadJWKS(path string) (*JWKS, error) {
+	s := &JWKS{path: filepath.Clean(path)}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload reads the key set file again. The current keys are kept if it fails.
+func (s *JWKS) Reload() error {
+	raw, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	keys, err := parseJWKS(raw)
+	if err != nil {
+		return fmt.Errorf("%s: %w", s.path, err)
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+	return nil
+}
+
+// Watch reloads the key set whenever its file changes, until ctx is done. The
+// directory is watched rather than the file, so that files replaced by a
+// rename, as editors and Kubernetes config maps do, are picked up too.
+func (s *JWKS) Watch(ctx context.Context, logger *slog.Logger) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	if err := watcher.Add(filepath.Dir(s.path)); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-watcher.Events:
+			if event.Has(fsnotify.Chmod) {
+				continue
+			}
+			if err := s.Reload(); err != nil {
+				logger.Warn("failed to reload JWKS, keeping the previous keys", slog.Any("err", err))
+				continue
+			}
+			logger.Info("reloaded JWKS", slog.String("path", s.path))
+		case err := <-watcher.Errors:
+			logger.Warn("JWKS watcher error", slog.Any("err", err))
+		}
+	}
+}
+
+// Keyfunc returns the key a token is to be verified with, for jwt.Parse. The
+// key is chosen by the kid header, which may be left out while the set holds a
+// single key.
+func (s *JWKS) Keyfunc(token *jwt.Token) (any, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	kid, _ := token.Header["kid"].(string)
+	k, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	if token.Method.Alg() != k.alg {
+		return nil, fmt.Errorf("key %q is not meant for %s", kid, token.Method.Alg())
+	}
+	return k.key, nil
+}
+
+// jwkAlgs maps the supported key types to the one algorithm each is used with.
+var jwkAlgs = map[string]string{
+	"oct": "HS256",
+	"RSA": "RS256",
+	"EC":  "ES256",
+}
+
+type rawJWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func parseJWKS(raw []byte) (map[string]jwk, error) {
+	var set struct {
+		Keys []rawJWK `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+	if len(set.Keys) == 0 {
+		return nil, errors.New("invalid JWKS: no keys")
+	}
+
+	keys := make(map[string]jwk, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if _, ok := keys[k.Kid]; ok {
+			return nil, fmt.Errorf("invalid JWKS: key %q appears more than once", k.Kid)
+		}
+		parsed, err := parseJWK(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWKS: key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = parsed
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("invalid JWKS: no signing keys")
+	}
+	// A lone key also verifies tokens that do not name their key.
+	if len(keys) == 1 {
+		keys[""] = slices.Collect(maps.Values(keys))[0]
+	}
+	return keys, nil
+}
+
+func parseJWK(k rawJWK) (jwk, error) {
+	alg, ok := jwkAlgs[k.Kty]
+	if !ok {
+		return jwk{}, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+	if k.Alg != "" && k.Alg != alg {
+		return jwk{}, fmt.Errorf("unsupported algorithm %q for key type %s", k.Alg, k.Kty)
+	}
+
+	decode := base64.RawURLEncoding.DecodeString
+	switch k.Kty {
+	case "oct":
+		secret, err := decode(k.K)
+		if err != nil || len(secret) < 32 {
+			return jwk{}, errors.New("k must hold at least 32 bytes")
+		}
+		return jwk{alg: alg, key: secret}, nil
+	case "RSA":
+		n, errN := decode(k.N)
+		e, errE := decode(k.E)
+		if errN != nil || errE != nil || len(n) < 256 || len(e) == 0 {
+			return jwk{}, errors.New("n and e must hold a key of at least 2048 bits")
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		return jwk{alg: alg, key: key}, nil
+	default: // EC
+		if k.Crv != "P-256" {
+			return jwk{}, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, errX := decode(k.X)
+		y, errY := decode(k.Y)
+		if errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+			return jwk{}, errors.New("x and y must hold 32 bytes each")
+		}
+		key, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), append(append([]byte{4}, x...), y...))
+		if err != nil {
+			return jwk{}, err
+		}
+		return jwk{alg: alg, key: key}, nil
+	}
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	hmacKey  = `{"kty":"oct","kid":"hs","k":"c2VjcmV0LXNlY3JldC1zZWNyZXQtc2VjcmV0LXNlY3JldA"}`
+	hmacJWKS = `{"keys":[` + hmacKey + `]}`
+)
+
+func writeJWKS(t *testing.T, path, content string) {
+	t.Helper()
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+// Given: A key set with an HMAC and an EC key
+func TestJWKS_Keyfunc(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	raw, _ := ecKey.PublicKey.Bytes()
+	b64 := base64.RawURLEncoding.EncodeToString
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path, `{"keys":[`+hmacKey+`,
+		{"kty":"EC","kid":"es","crv":"P-256","x":"`+b64(raw[1:33])+`","y":"`+b64(raw[33:])+`"}
+	]}`)
+	jwks, err := LoadJWKS(path)
+	assert.NoError(t, err)
+
+	withKid := func(method jwt.SigningMethod, kid string) *jwt.Token {
+		token := jwt.New(method)
+		token.Header["kid"] = kid
+		return token
+	}
+
+	// When: Tokens are verified with the set
+	esToken, _ := withKid(jwt.SigningMethodES256, "es").SignedString(ecKey)
+	_, esErr := jwt.Parse(esToken, jwks.Keyfunc)
+	_, mismatchErr := jwks.Keyfunc(withKid(jwt.SigningMethodHS256, "es"))
+	_, unknownErr := jwks.Keyfunc(withKid(jwt.SigningMethodHS256, "other"))
+	_, noKidErr := jwks.Keyfunc(jwt.New(jwt.SigningMethodHS256))
+
+	// Then: Only the named key should verify, and only with its own algorithm
+	assert.NoError(t, esErr)
+	assert.ErrorContains(t, mismatchErr, "not meant for HS256")
+	assert.ErrorContains(t, unknownErr, "unknown key")
+	assert.Error(t, noKidErr, "expected a kid to be needed with more than one key")
+}
+
+// Given: Invalid key sets
+func TestJWKS_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		jwks string
+		want string
+	}{
+		"empty":       {`{"keys":[]}`, "no keys"},
+		"short hmac":  {`{"keys":[{"kty":"oct","k":"c2hvcnQ"}]}`, "at least 32 bytes"},
+		"wrong alg":   {`{"keys":[{"kty":"oct","alg":"none","k":"c2VjcmV0LXNlY3JldC1zZWNyZXQtc2VjcmV0LXNlY3JldA"}]}`, `unsupported algorithm "none"`},
+		"unknown kty": {`{"keys":[{"kty":"OKP"}]}`, `unsupported key type "OKP"`},
+		"duplicate":   {`{"keys":[` + hmacKey + `,` + hmacKey + `]}`, "more than once"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "jwks.json")
+			writeJWKS(t, path, tt.jwks)
+
+			// When: The set is loaded
+			_, err := LoadJWKS(path)
+
+			// Then: The problem should be reported
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+// Given: A watched key set file
+func TestJWKS_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path, hmacJWKS)
+	jwks, err := LoadJWKS(path)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = jwks.Watch(ctx, slog.New(slog.NewTextHandler(io.Discard, nil))) }()
+	time.Sleep(50 * time.Millisecond)
+	token := jwt.New(jwt.SigningMethodHS256)
+	token.Header["kid"] = "rotated"
+
+	// When: A broken file and then a file with a new key are written
+	writeJWKS(t, path, `{"keys":`)
+	time.Sleep(50 * time.Millisecond)
+	_, brokenErr := jwks.Keyfunc(jwt.New(jwt.SigningMethodHS256))
+	writeJWKS(t, path, `{"keys":[{"kty":"oct","kid":"rotated","k":"c2VjcmV0LXNlY3JldC1zZWNyZXQtc2VjcmV0LXNlY3JldA"}]}`)
+
+	// Then: The broken file should be ignored and the new key picked up
+	assert.NoError(t, brokenErr, "expected the previous keys to be kept")
+	assert.Eventually(t, func() bool {
+		_, err := jwks.Keyfunc(token)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownKey is returned when a presented API key matches no known key.
+var ErrUnknownKey = errors.New("unknown API key")
+
+// Key is a named API key and the scopes it grants. The name identifies the
+// integration using the key, so it is safe to log.
+type Key struct {
+	// ID tells the key apart from every other key, unlike Name, which a
+	// rotated key shares with its successor. Requests are audited and rate
+	// limited by it.
+	ID     string
+	Name   string
+	Scopes []Scope
+}
+
+// LegacyKeyName is the name of the key in X_API_KEY. Stored keys cannot take
+// it.
+const LegacyKeyName = "default"
+
+// KeyStore finds the key matching the secret a request presented.
+type KeyStore interface {
+	Lookup(ctx context.Context, secret string) (*Key, error)
+}
+
+// StaticKeyStore holds a set of keys given at startup, which can be replaced
+// when the secrets they are read from change.
+type StaticKeyStore struct {
+	mu sync.RWMutex
+	// keys are indexed by the SHA-256 of their secret, so that looking one up
+	// does not compare the secrets themselves.
+	keys map[[sha256.Size]byte]Key
+}
+
+// NewStaticKeyStore returns a StaticKeyStore holding keys, indexed by their
+// secret.
+func NewStaticKeyStore(keys map[string]Key) *StaticKeyStore {
+	s := &StaticKeyStore{}
+	s.Replace(keys)
+	return s
+}
+
+// Replace swaps the keys held for keys, indexed by their secret.
+func (s *StaticKeyStore) Replace(keys map[string]Key) {
+	hashed := make(map[[sha256.Size]byte]Key, len(keys))
+	for secret, key := range keys {
+		hashed[sha256.Sum256([]byte(secret))] = key
+	}
+	s.mu.Lock()
+	s.keys = hashed
+	s.mu.Unlock()
+}
+
+func (s *StaticKeyStore) Lookup(_ context.Context, secret string) (*Key, error) {
+	s.mu.RLock()
+	key, ok := s.keys[sha256.Sum256([]byte(secret))]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return &key, nil
+}
+
+// keyPrefix starts every generated API key, so that leaked keys are easy to
+// recognise.
+const keyPrefix = "cruder_"
+
+// GenerateKey returns a new random API key and its public prefix.
+func GenerateKey() (key, prefix string, err error) {
+	id := make([]byte, 6)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	prefix = keyPrefix + hex.EncodeToString(id)
+	return prefix + "_" + base64.RawURLEncoding.EncodeToString(secret), prefix, nil
+}
+
+// KeyPrefix returns the public prefix of a key made by GenerateKey.
+func KeyPrefix(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, keyPrefix)
+	if !ok {
+		return "", false
+	}
+	id, _, ok := strings.Cut(rest, "_")
+	if !ok || len(id) != 12 {
+		return "", false
+	}
+	return keyPrefix + id, true
+}
+
+// NewSalt returns a random salt for HashKey.
+func NewSalt() ([]byte, error) {
+	salt := make([]byte, 16)
+	_, err := rand.Read(salt)
+	return salt, err
+}
+
+// HashKey returns the salted hash of key that is stored in its place.
+func HashKey(salt []byte, key string) []byte {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(key))
+	return h.Sum(nil)
+}
+
+// KeyMatches reports whether key hashes to hash, in constant time.
+func KeyMatches(salt, hash []byte, key string) bool {
+	return subtle.ConstantTimeCompare(HashKey(salt, key), hash) == 1
+}
+
+type multiKeyStore []KeyStore
+
+// NewMultiKeyStore returns a KeyStore that asks each of stores in turn.
+func NewMultiKeyStore(stores ...KeyStore) KeyStore {
+	return multiKeyStore(stores)
+}
+
+func (m multiKeyStore) Lookup(ctx context.Context, secret string) (*Key, error) {
+	for _, s := range m {
+		key, err := s.Lookup(ctx, secret)
+		if !errors.Is(err, ErrUnknownKey) {
+			return key, err
+		}
+	}
+	return nil, ErrUnknownKey
+}
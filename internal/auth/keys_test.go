@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Given: A generated key and its salted hash
+func TestGenerateKey(t *testing.T) {
+	key, prefix, err := GenerateKey()
+	assert.NoError(t, err)
+	salt, _ := NewSalt()
+	hash := HashKey(salt, key)
+
+	// When: The prefix is read back and the key is checked
+	gotPrefix, ok := KeyPrefix(key)
+
+	// Then: The prefix should match and only the key itself should match the hash
+	assert.True(t, ok)
+	assert.Equal(t, prefix, gotPrefix)
+	assert.True(t, KeyMatches(salt, hash, key))
+	assert.False(t, KeyMatches(salt, hash, key+"x"))
+	_, ok = KeyPrefix("supersecret")
+	assert.False(t, ok)
+}
+
+type failingKeyStore struct{ err error }
+
+func (s failingKeyStore) Lookup(context.Context, string) (*Key, error) { return nil, s.err }
+
+// Given: Key stores chained together
+func TestMultiKeyStore(t *testing.T) {
+	static := NewStaticKeyStore(map[string]Key{"secret": {Name: "default"}})
+	down := errors.New("database is down")
+
+	// When: Looking up keys through the chain
+	found, err := NewMultiKeyStore(failingKeyStore{ErrUnknownKey}, static).Lookup(context.Background(), "secret")
+	_, unknownErr := NewMultiKeyStore(failingKeyStore{ErrUnknownKey}, static).Lookup(context.Background(), "other")
+	_, downErr := NewMultiKeyStore(failingKeyStore{down}, static).Lookup(context.Background(), "secret")
+
+	// Then: Unknown keys should fall through and other errors should stop the lookup
+	assert.NoError(t, err)
+	assert.Equal(t, "default", found.Name)
+	assert.ErrorIs(t, unknownErr, ErrUnknownKey)
+	assert.ErrorIs(t, downErr, down)
+}
+
+// Given: A static key store
+func TestStaticKeyStore_Replace(t *testing.T) {
+	static := NewStaticKeyStore(map[string]Key{"old": {Name: "default"}})
+
+	// When: Its keys are replaced after a rotation
+	static.Replace(map[string]Key{"new": {Name: "default"}})
+
+	// Then: Only the new secret should be accepted
+	_, oldErr := static.Lookup(context.Background(), "old")
+	found, err := static.Lookup(context.Background(), "new")
+	assert.ErrorIs(t, oldErr, ErrUnknownKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "default", found.Name)
+}
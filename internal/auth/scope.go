@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// Scope is a permission granted to a caller.
+type Scope string
+
+const (
+	ScopeUsersRead  Scope = "users:read"
+	ScopeUsersWrite Scope = "users:write"
+	ScopeAuditRead  Scope = "audit:read"
+	ScopeKeysAdmin  Scope = "keys:admin"
+)
+
+// AllScopes lists every scope a caller can be granted.
+var AllScopes = []Scope{ScopeUsersRead, ScopeUsersWrite, ScopeAuditRead, ScopeKeysAdmin}
+
+// ParseScope returns the scope named s, or an error if there is no such scope.
+func ParseScope(s string) (Scope, error) {
+	scope := Scope(s)
+	if !slices.Contains(AllScopes, scope) {
+		return "", fmt.Errorf("unknown scope %q", s)
+	}
+	return scope, nil
+}
+
+type scopesKey struct{}
+
+// WithScopes returns a copy of ctx that carries the scopes granted to the caller.
+func WithScopes(ctx context.Context, scopes []Scope) context.Context {
+	return context.WithValue(ctx, scopesKey{}, scopes)
+}
+
+// HasScope reports whether ctx carries scope.
+func HasScope(ctx context.Context, scope Scope) bool {
+	scopes, _ := ctx.Value(scopesKey{}).([]Scope)
+	return slices.Contains(scopes, scope)
+}
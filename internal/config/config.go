@@ -1,54 +1,284 @@
 package config
 
 import (
+	"cruder/internal/auth"
 	"errors"
 	"fmt"
-	"os"
+	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type Config struct {
+	// SecretsDir holds a file per secret named after its environment
+	// variable, as Docker and Kubernetes mount them. See Secret.
+	SecretsDir string `mapstructure:"secrets_dir"`
+	Server     struct {
+		// Addr is the address the HTTP server listens on, ":8080" by
+		// default.
+		Addr              string        `mapstructure:"addr"`
+		ReadHeaderTimeout time.Duration `mapstructure:"read_header_timeout"`
+		ReadTimeout       time.Duration `mapstructure:"read_timeout"`
+		WriteTimeout      time.Duration `mapstructure:"write_timeout"`
+		IdleTimeout       time.Duration `mapstructure:"idle_timeout"`
+		// DrainDelay is how long readiness fails on shutdown before the
+		// server stops accepting connections.
+		DrainDelay time.Duration `mapstructure:"drain_delay"`
+		// ShutdownTimeout is how long in-flight requests get to finish on
+		// shutdown.
+		ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+		// TrustedProxies lists the IPs and CIDRs of the proxies whose
+		// X-Forwarded-For header names the client. Requests from anywhere
+		// else are attributed to their remote address.
+		TrustedProxies []string `mapstructure:"trusted_proxies"`
+	}
 	Database struct {
 		Host    string `mapstructure:"host"`
 		DB      string `mapstructure:"db"`
 		Port    string `mapstructure:"port"`
 		User    string `mapstructure:"user"`
 		Sslmode string `mapstructure:"sslmode"`
+		// Pool tunes the connection pool. Zero values keep the
+		// database/sql defaults.
+		Pool struct {
+			MaxOpenConns    int           `mapstructure:"max_open_conns"`
+			MaxIdleConns    int           `mapstructure:"max_idle_conns"`
+			ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+			ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time"`
+		} `mapstructure:"pool"`
+		// Connect controls how long startup waits for the database, retrying
+		// with exponential backoff.
+		Connect struct {
+			// Timeout is the total time given to connect.
+			Timeout        time.Duration `mapstructure:"timeout"`
+			InitialBackoff time.Duration `mapstructure:"initial_backoff"`
+			MaxBackoff     time.Duration `mapstructure:"max_backoff"`
+		} `mapstructure:"connect"`
+	}
+	Users struct {
+		// LegacyIDRoutes keeps the integer ID routes available next to the
+		// UUID ones while clients migrate.
+		LegacyIDRoutes bool `mapstructure:"legacy_id_routes"`
+		// DeletedRetention is how long soft deleted users are kept before
+		// they can be purged. Zero disables purging.
+		DeletedRetention time.Duration `mapstructure:"deleted_retention"`
+	}
+	Logging struct {
+		// Level is debug, info, warn or error.
+		Level string `mapstructure:"level"`
+		// Format is json or text.
+		Format string `mapstructure:"format"`
+	}
+	Auth struct {
+		APIKeys []APIKey `mapstructure:"api_keys"`
+		// JWT enables Authorization: Bearer tokens next to API keys when a
+		// JWKS file is set.
+		JWT struct {
+			// JWKSFile holds the keys tokens are verified with. It is
+			// reloaded whenever it changes.
+			JWKSFile string `mapstructure:"jwks_file"`
+			Issuer   string `mapstructure:"issuer"`
+			Audience string `mapstructure:"audience"`
+			// ScopeClaim names the claim holding the granted scopes,
+			// "scope" by default.
+			ScopeClaim string `mapstructure:"scope_claim"`
+		} `mapstructure:"jwt"`
 	}
+	// RateLimits limits the requests of each caller per route group: users,
+	// audit and api_keys. The default entry applies to the groups without
+	// an entry of their own.
+	RateLimits map[string]RateLimit `mapstructure:"rate_limits"`
+	// Tracing exports OpenTelemetry spans of requests, service calls and
+	// SQL statements.
+	Tracing struct {
+		// Exporter is none, stdout or otlp.
+		Exporter string `mapstructure:"exporter"`
+		// Endpoint is the URL of the OTLP/HTTP traces endpoint. When empty
+		// the standard OTEL_EXPORTER_OTLP_* environment variables apply.
+		Endpoint string `mapstructure:"endpoint"`
+		// SampleRatio is the fraction of new traces that are recorded, 1 by
+		// default. Zero records none.
+		SampleRatio float64 `mapstructure:"sample_ratio"`
+	}
+
+	// settings holds every setting as loaded, for Print.
+	settings map[string]any
+}
+
+// RateLimit allows Burst requests at once, refilled at Rate requests per
+// second. A zero rate disables limiting.
+type RateLimit struct {
+	Rate  float64 `mapstructure:"rate"`
+	Burst int     `mapstructure:"burst"`
+}
+
+// APIKey describes a named API key. The secret itself is read from the
+// environment variable named by KeyEnv, or its file as described by Secret,
+// so that it stays out of the config file. Revoking a key means removing its entry.
+type APIKey struct {
+	Name   string   `mapstructure:"name"`
+	KeyEnv string   `mapstructure:"key_env"`
+	Scopes []string `mapstructure:"scopes"`
 }
 
+// LogLevel returns the level set in logging.level, which Validate checks.
+func (c *Config) LogLevel() slog.Level {
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(c.Logging.Level))
+	return level
+}
+
+// GetDSN returns the connection string of the database. The password is read
+// anew on every call, see Secret.
 func (c *Config) GetDSN() (string, error) {
-	password := os.Getenv("POSTGRES_PASSWORD")
-	if password == "" {
-		return "", errors.New("POSTGRES_PASSWORD environment variable is not set")
+	password, err := c.Secret(PostgresPasswordEnv)
+	if err != nil {
+		return "", err
+	}
+	return "host=" + quoteDSNValue(c.Database.Host) +
+		" port=" + quoteDSNValue(c.Database.Port) +
+		" user=" + quoteDSNValue(c.Database.User) +
+		" password=" + quoteDSNValue(password) +
+		" dbname=" + quoteDSNValue(c.Database.DB) +
+		" sslmode=" + quoteDSNValue(c.Database.Sslmode), nil
+}
+
+// dsnValueEscaper escapes the characters libpq treats specially inside a
+// quoted connection string value.
+var dsnValueEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// quoteDSNValue quotes a connection string value, so that spaces, quotes or
+// an embedded "key=value" in a password cannot break the connection string.
+func quoteDSNValue(value string) string {
+	return "'" + dsnValueEscaper.Replace(value) + "'"
+}
+
+// GetAPIKeys returns the configured API keys indexed by their secret. The key
+// in X_API_KEY, if set, is kept working under the name "default" with every
+// scope. These keys are checked after the ones in the api_keys table and are
+// meant for bootstrapping it; there may be none.
+func (c *Config) GetAPIKeys() (map[string]auth.Key, error) {
+	keys := make(map[string]auth.Key, len(c.Auth.APIKeys)+1)
+	names := make(map[string]bool, len(c.Auth.APIKeys)+1)
+	add := func(secret string, key auth.Key) error {
+		if names[key.Name] {
+			return fmt.Errorf("API key %q is configured more than once", key.Name)
+		}
+		if _, ok := keys[secret]; ok {
+			return fmt.Errorf("API key %q has the same secret as another key", key.Name)
+		}
+		names[key.Name] = true
+		keys[secret] = key
+		return nil
+	}
+
+	legacy, err := c.Secret(APIKeyEnv)
+	if err != nil {
+		return nil, err
+	}
+	if legacy != "" {
+		_ = add(legacy, auth.Key{ID: configKeyID(auth.LegacyKeyName), Name: auth.LegacyKeyName, Scopes: auth.AllScopes})
 	}
-	dsn := "host=" + c.Database.Host +
-		" port=" + c.Database.Port +
-		" user=" + c.Database.User +
-		" password=" + password +
-		" dbname=" + c.Database.DB +
-		" sslmode=" + c.Database.Sslmode
+	for _, k := range c.Auth.APIKeys {
+		if k.Name == "" {
+			return nil, errors.New("API key without a name")
+		}
+		if k.KeyEnv == "" {
+			return nil, fmt.Errorf("API key %q: key_env is not set", k.Name)
+		}
+		secret, err := c.Secret(k.KeyEnv)
+		if err != nil {
+			return nil, fmt.Errorf("API key %q: %w", k.Name, err)
+		}
+		if secret == "" {
+			return nil, fmt.Errorf("API key %q: environment variable %q is not set", k.Name, k.KeyEnv)
+		}
+		key := auth.Key{ID: configKeyID(k.Name), Name: k.Name, Scopes: make([]auth.Scope, len(k.Scopes))}
+		for i, s := range k.Scopes {
+			scope, err := auth.ParseScope(s)
+			if err != nil {
+				return nil, fmt.Errorf("API key %q: %w", k.Name, err)
+			}
+			key.Scopes[i] = scope
+		}
+		if err := add(secret, key); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+// configKeyID identifies a configured key. It cannot clash with the prefix
+// identifying a stored key, which holds no colon.
+func configKeyID(name string) string {
+	return "config:" + name
+}
 
-	return dsn, nil
+// defaults apply to the settings missing from the config file. Registering
+// a setting here also lets it be set from the environment alone, and shows it
+// in Print.
+var defaults = map[string]any{
+	"secrets_dir":                      "",
+	"server.addr":                      ":8080",
+	"server.read_header_timeout":       "5s",
+	"server.read_timeout":              "30s",
+	"server.write_timeout":             "30s",
+	"server.idle_timeout":              "120s",
+	"server.drain_delay":               "5s",
+	"server.shutdown_timeout":          "20s",
+	"database.host":                    "",
+	"database.db":                      "",
+	"database.port":                    "5432",
+	"database.user":                    "",
+	"database.sslmode":                 "require",
+	"database.pool.max_open_conns":     20,
+	"database.pool.max_idle_conns":     10,
+	"database.pool.conn_max_lifetime":  "30m",
+	"database.pool.conn_max_idle_time": "5m",
+	"database.connect.timeout":         "60s",
+	"database.connect.initial_backoff": "500ms",
+	"database.connect.max_backoff":     "10s",
+	"users.legacy_id_routes":           false,
+	"users.deleted_retention":          "0s",
+	"logging.level":                    "info",
+	"logging.format":                   "json",
+	"auth.jwt.jwks_file":               "",
+	"auth.jwt.issuer":                  "",
+	"auth.jwt.audience":                "",
+	"auth.jwt.scope_claim":             "scope",
+	"tracing.exporter":                 "none",
+	"tracing.endpoint":                 "",
+	"tracing.sample_ratio":             1,
 }
 
+// LoadConfig reads config.yaml from the config directory or the working
+// directory. Every setting can be overridden from the environment by its
+// path in upper case with dots replaced by underscores, e.g. SERVER_ADDR.
 func LoadConfig() (*Config, error) {
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-	viper.AutomaticEnv()
-	viper.AddConfigPath("config")
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
-	viper.AddConfigPath(".")
-
-	if err := viper.ReadInConfig(); err != nil {
+	v := viper.New()
+	v.AddConfigPath("config")
+	v.AddConfigPath(".")
+	v.SetConfigName("config")
+	v.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("error reading config file: %w", err)
 	}
+	return load(v)
+}
+
+func load(v *viper.Viper) (*Config, error) {
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	for key, value := range defaults {
+		v.SetDefault(key, value)
+	}
 
 	var cfg Config
-	if err := viper.Unmarshal(&cfg); err != nil {
+	if err := v.Unmarshal(&cfg); err != nil {
 		return nil, fmt.Errorf("unable to decode config into struct: %w", err)
 	}
+	cfg.settings = v.AllSettings()
 	return &cfg, nil
 }
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"bytes"
+	"cruder/internal/auth"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+const testConfig = `
+database:
+  host: localhost
+  db: postgres
+  port: "5432"
+  user: postgres
+  sslmode: disable
+rate_limits:
+  default:
+    rate: 20
+    burst: 40
+`
+
+// helper to load a config from YAML the way LoadConfig does
+func loadTestConfig(t *testing.T, content string) *Config {
+	t.Helper()
+	v := viper.New()
+	v.SetConfigType("yaml")
+	assert.NoError(t, v.ReadConfig(strings.NewReader(content)))
+	cfg, err := load(v)
+	assert.NoError(t, err)
+	return cfg
+}
+
+// Given: A config file, secrets and an override in the environment
+func TestLoad_DefaultsAndEnvironment(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "db-secret")
+	t.Setenv("X_API_KEY", "legacy")
+	t.Setenv("SERVER_WRITE_TIMEOUT", "1m")
+
+	// When: The config is loaded
+	cfg := loadTestConfig(t, testConfig)
+
+	// Then: Defaults should fill the gaps and the environment should win
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, ":8080", cfg.Server.Addr)
+	assert.Equal(t, time.Minute, cfg.Server.WriteTimeout)
+	assert.Equal(t, 20, cfg.Database.Pool.MaxOpenConns)
+	assert.Equal(t, "json", cfg.Logging.Format)
+	dsn, err := cfg.GetDSN()
+	assert.NoError(t, err)
+	assert.Contains(t, dsn, "password='db-secret'")
+}
+
+// Given: A password with characters that are special in a connection string
+func TestGetDSN_QuotesValues(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", `p a'ss\ host=evil`)
+	cfg := loadTestConfig(t, testConfig)
+
+	// When: The DSN is built
+	dsn, err := cfg.GetDSN()
+
+	// Then: Every value should be quoted and the password escaped
+	assert.NoError(t, err)
+	assert.Equal(t, `host='localhost' port='5432' user='postgres' password='p a\'ss\\ host=evil' dbname='postgres' sslmode='disable'`, dsn)
+	_, err = pq.NewConnector(dsn)
+	assert.NoError(t, err)
+}
+
+// Given: A config with several mistakes
+func TestValidate_ReportsEveryProblem(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "")
+	cfg := loadTestConfig(t, testConfig+`
+server:
+  addr: "8080"
+  shutdown_timeout: 0s
+  trusted_proxies: ["10.244.0.0/16", "ingress"]
+logging:
+  level: verbose
+tracing:
+  exporter: zipkin
+`)
+	cfg.Database.Pool.MaxIdleConns = 50
+	cfg.Database.Connect.MaxBackoff = 100 * time.Millisecond
+	cfg.RateLimits["users"] = RateLimit{Rate: 10}
+
+	// When: The config is validated
+	err := cfg.Validate()
+
+	// Then: Every mistake should be reported at once
+	var verr *ValidationError
+	assert.ErrorAs(t, err, &verr)
+	assert.Equal(t, []string{
+		`server.addr must be a host:port address like :8080, got "8080"`,
+		"server.shutdown_timeout must be positive, got 0s",
+		`server.trusted_proxies must hold IPs or CIDRs, got "ingress"`,
+		"database.password is required, set POSTGRES_PASSWORD, POSTGRES_PASSWORD_FILE or secrets_dir",
+		"database.pool.max_idle_conns (50) must not exceed database.pool.max_open_conns (20)",
+		"database.connect.max_backoff (100ms) must not be less than database.connect.initial_backoff (500ms)",
+		`logging.level must be one of debug, info, warn, error, got "verbose"`,
+		"rate_limits.users.burst must be at least 1, got 0",
+		`tracing.exporter must be one of none, stdout, otlp, got "zipkin"`,
+	}, verr.Problems)
+}
+
+// Given: A config with secrets set
+func TestPrint_RedactsSecrets(t *testing.T) {
+	t.Setenv("POSTGRES_PASSWORD", "db-secret")
+	t.Setenv("X_API_KEY", "")
+	cfg := loadTestConfig(t, testConfig)
+
+	// When: The config is printed
+	var buf bytes.Buffer
+	assert.NoError(t, cfg.Print(&buf))
+
+	// Then: The set secret should be redacted and the rest shown
+	out := buf.String()
+	assert.NotContains(t, out, "db-secret")
+	assert.Contains(t, out, "password: REDACTED")
+	assert.Contains(t, out, `api_key: ""`)
+	assert.Contains(t, out, "host: localhost")
+	assert.Contains(t, out, "write_timeout: 30s")
+}
+
+// Given: The legacy key and a named read only key
+func TestGetAPIKeys(t *testing.T) {
+	t.Setenv("X_API_KEY", "legacy")
+	t.Setenv("CRM_API_KEY", "crm-secret")
+	var cfg Config
+	cfg.Auth.APIKeys = []APIKey{{Name: "crm", KeyEnv: "CRM_API_KEY", Scopes: []string{"users:read"}}}
+
+	// When: The keys are loaded
+	keys, err := cfg.GetAPIKeys()
+
+	// Then: Both keys should be indexed by their secret
+	assert.NoError(t, err)
+	assert.Equal(t, auth.Key{ID: "config:default", Name: "default", Scopes: auth.AllScopes}, keys["legacy"])
+	assert.Equal(t, auth.Key{ID: "config:crm", Name: "crm", Scopes: []auth.Scope{auth.ScopeUsersRead}}, keys["crm-secret"])
+}
+
+// Given: Misconfigured API keys
+func TestGetAPIKeys_Invalid(t *testing.T) {
+	t.Setenv("CRM_API_KEY", "crm-secret")
+	t.Setenv("ERP_API_KEY", "crm-secret")
+
+	tests := map[string]struct {
+		keys []APIKey
+		want string
+	}{
+		"unset secret":   {[]APIKey{{Name: "crm", KeyEnv: "MISSING_API_KEY"}}, `"MISSING_API_KEY" is not set`},
+		"unknown scope":  {[]APIKey{{Name: "crm", KeyEnv: "CRM_API_KEY", Scopes: []string{"users:admin"}}}, `unknown scope "users:admin"`},
+		"duplicate name": {[]APIKey{{Name: "crm", KeyEnv: "CRM_API_KEY"}, {Name: "crm", KeyEnv: "CRM_API_KEY"}}, "more than once"},
+		"shared secret":  {[]APIKey{{Name: "crm", KeyEnv: "CRM_API_KEY"}, {Name: "erp", KeyEnv: "ERP_API_KEY"}}, "same secret"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var cfg Config
+			cfg.Auth.APIKeys = tt.keys
+
+			// When: The keys are loaded
+			_, err := cfg.GetAPIKeys()
+
+			// Then: The misconfiguration should be reported
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
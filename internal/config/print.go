@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// redacted replaces the secrets in a printed config.
+const redacted = "REDACTED"
+
+// Print writes the effective config, after defaults and environment
+// overrides, as YAML with the secrets redacted.
+func (c *Config) Print(w io.Writer) error {
+	secrets := make(map[string]any, len(secretSettings))
+	for path, env := range secretSettings {
+		secrets[path] = ""
+		if value, _ := c.Secret(env); value != "" {
+			secrets[path] = redacted
+		}
+	}
+
+	out, err := yaml.Marshal(redact(c.settings, "", secrets))
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// redact returns a copy of settings, whose keys start with prefix, with the
+// secrets set to the given values.
+func redact(settings map[string]any, prefix string, secrets map[string]any) map[string]any {
+	out := make(map[string]any, len(settings))
+	for key, value := range settings {
+		out[key] = value
+		if nested, ok := value.(map[string]any); ok {
+			out[key] = redact(nested, prefix+key+".", secrets)
+		}
+	}
+	for path, value := range secrets {
+		key, ok := strings.CutPrefix(path, prefix)
+		if !ok {
+			continue
+		}
+		if section, _, nested := strings.Cut(key, "."); nested {
+			if _, ok := out[section]; !ok {
+				out[section] = redact(map[string]any{}, prefix+section+".", secrets)
+			}
+			continue
+		}
+		out[key] = value
+	}
+	return out
+}
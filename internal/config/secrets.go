@@ -0,0 +1,112 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// The environment variables the built-in secrets are known by.
+const (
+	PostgresPasswordEnv = "POSTGRES_PASSWORD"
+	APIKeyEnv           = "X_API_KEY"
+)
+
+// secretSettings places the built-in secrets in the printed config.
+var secretSettings = map[string]string{
+	"database.password": PostgresPasswordEnv,
+	"auth.api_key":      APIKeyEnv,
+}
+
+// Secret returns the secret known by the environment variable name. It is
+// taken from the variable itself, from the file NAME_FILE points to, or from
+// the file NAME in secrets_dir, whichever is found first; setting both NAME
+// and NAME_FILE is an error. Files are read on every call, so that a rotated
+// secret is picked up without a restart. An unset secret is empty.
+func (c *Config) Secret(name string) (string, error) {
+	value, path := os.Getenv(name), os.Getenv(name+"_FILE")
+	switch {
+	case value != "" && path != "":
+		return "", fmt.Errorf("%s and %s_FILE are both set", name, name)
+	case value != "":
+		return value, nil
+	case path == "" && c.SecretsDir != "":
+		path = filepath.Join(c.SecretsDir, name)
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			return "", nil
+		}
+	case path == "":
+		return "", nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	// Files written by hand or by echo tend to end with a newline.
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// secretDirs returns the directories the secret files live in.
+func (c *Config) secretDirs() []string {
+	names := []string{PostgresPasswordEnv, APIKeyEnv}
+	for _, k := range c.Auth.APIKeys {
+		names = append(names, k.KeyEnv)
+	}
+
+	var dirs []string
+	if c.SecretsDir != "" {
+		dirs = append(dirs, c.SecretsDir)
+	}
+	for _, name := range names {
+		if path := os.Getenv(name + "_FILE"); path != "" {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+	}
+	slices.Sort(dirs)
+	return slices.Compact(dirs)
+}
+
+// WatchSecrets calls reload whenever a file secrets are read from changes,
+// until ctx is done. The directories holding the files are watched rather than
+// the files themselves, as Kubernetes swaps mounted secrets by replacing a
+// symlink.
+func (c *Config) WatchSecrets(ctx context.Context, logger *slog.Logger, reload func()) error {
+	dirs := c.secretDirs()
+	if len(dirs) == 0 {
+		return nil
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	for _, dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-watcher.Events:
+			if event.Has(fsnotify.Chmod) {
+				continue
+			}
+			logger.Info("secret files changed, reloading", slog.String("path", event.Name))
+			reload()
+		case err := <-watcher.Errors:
+			logger.Warn("secrets watcher error", slog.Any("err", err))
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// helper to write a secret file
+func writeSecret(t *testing.T, path, content string) {
+	t.Helper()
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+// Given: A secret set in the environment, a _FILE variable or secrets_dir
+func TestSecret_Sources(t *testing.T) {
+	dir := t.TempDir()
+	writeSecret(t, filepath.Join(dir, "FROM_FILE"), "file-secret\n")
+	writeSecret(t, filepath.Join(dir, "FROM_DIR"), "dir-secret")
+	writeSecret(t, filepath.Join(dir, "BOTH"), "dir-secret")
+	t.Setenv("FROM_ENV", "env-secret")
+	t.Setenv("FROM_FILE_FILE", filepath.Join(dir, "FROM_FILE"))
+	t.Setenv("BOTH", "env-secret")
+	t.Setenv("BOTH_FILE", filepath.Join(dir, "BOTH"))
+	t.Setenv("MISSING_FILE", filepath.Join(dir, "missing"))
+	cfg := Config{SecretsDir: dir}
+
+	tests := map[string]struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		"environment":  {name: "FROM_ENV", want: "env-secret"},
+		"file":         {name: "FROM_FILE", want: "file-secret"},
+		"secrets dir":  {name: "FROM_DIR", want: "dir-secret"},
+		"unset":        {name: "UNSET", want: ""},
+		"both set":     {name: "BOTH", wantErr: "BOTH and BOTH_FILE are both set"},
+		"missing file": {name: "MISSING", wantErr: "failed to read MISSING"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// When: The secret is read
+			got, err := cfg.Secret(tt.name)
+
+			// Then: It should come from the source that is set
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// Given: The database password and the legacy API key mounted as files
+func TestSecret_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	writeSecret(t, filepath.Join(dir, "POSTGRES_PASSWORD"), "old-password")
+	t.Setenv("POSTGRES_PASSWORD", "")
+	t.Setenv("X_API_KEY", "")
+	t.Setenv("X_API_KEY_FILE", filepath.Join(dir, "api-key"))
+	writeSecret(t, filepath.Join(dir, "api-key"), "old-key")
+	cfg := loadTestConfig(t, testConfig+"secrets_dir: "+dir+"\n")
+	assert.NoError(t, cfg.Validate())
+
+	reloaded := make(chan struct{}, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = cfg.WatchSecrets(ctx, slog.New(slog.DiscardHandler), func() { reloaded <- struct{}{} })
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// When: The secrets are rotated
+	writeSecret(t, filepath.Join(dir, "POSTGRES_PASSWORD"), "new-password")
+	writeSecret(t, filepath.Join(dir, "api-key"), "new-key")
+
+	// Then: The watcher should notice and the new secrets should be read
+	select {
+	case <-reloaded:
+	case <-time.After(time.Second):
+		t.Fatal("expected the secrets to be reloaded")
+	}
+	dsn, err := cfg.GetDSN()
+	assert.NoError(t, err)
+	assert.Contains(t, dsn, "password='new-password'")
+	keys, err := cfg.GetAPIKeys()
+	assert.NoError(t, err)
+	assert.Contains(t, keys, "new-key")
+}
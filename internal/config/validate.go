@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	sslModes         = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+	logFormats       = []string{"json", "text"}
+	tracingExporters = []string{"none", "stdout", "otlp"}
+)
+
+// ValidationError lists every problem found in a config.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks the whole config and reports every problem at once, so that
+// a broken deployment can be fixed in one go.
+func (c *Config) Validate() error {
+	var problems []string
+	problem := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	nonNegative := func(name string, d time.Duration) {
+		if d < 0 {
+			problem("%s must not be negative, got %s", name, d)
+		}
+	}
+	required := func(name, value string) {
+		if value == "" {
+			problem("%s is required", name)
+		}
+	}
+	oneOf := func(name, value string, allowed []string) {
+		if !slices.Contains(allowed, value) {
+			problem("%s must be one of %s, got %q", name, strings.Join(allowed, ", "), value)
+		}
+	}
+
+	if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		problem("server.addr must be a host:port address like :8080, got %q", c.Server.Addr)
+	}
+	nonNegative("server.read_header_timeout", c.Server.ReadHeaderTimeout)
+	nonNegative("server.read_timeout", c.Server.ReadTimeout)
+	nonNegative("server.write_timeout", c.Server.WriteTimeout)
+	nonNegative("server.idle_timeout", c.Server.IdleTimeout)
+	nonNegative("server.drain_delay", c.Server.DrainDelay)
+	if c.Server.ShutdownTimeout <= 0 {
+		problem("server.shutdown_timeout must be positive, got %s", c.Server.ShutdownTimeout)
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			problem("server.trusted_proxies must hold IPs or CIDRs, got %q", proxy)
+		}
+	}
+
+	required("database.host", c.Database.Host)
+	required("database.port", c.Database.Port)
+	required("database.user", c.Database.User)
+	required("database.db", c.Database.DB)
+	oneOf("database.sslmode", c.Database.Sslmode, sslModes)
+	if password, err := c.Secret(PostgresPasswordEnv); err != nil {
+		problem("database.password: %v", err)
+	} else if password == "" {
+		problem("database.password is required, set POSTGRES_PASSWORD, POSTGRES_PASSWORD_FILE or secrets_dir")
+	}
+	pool := c.Database.Pool
+	if pool.MaxOpenConns < 0 {
+		problem("database.pool.max_open_conns must not be negative, got %d", pool.MaxOpenConns)
+	}
+	if pool.MaxIdleConns < 0 {
+		problem("database.pool.max_idle_conns must not be negative, got %d", pool.MaxIdleConns)
+	}
+	if pool.MaxOpenConns > 0 && pool.MaxIdleConns > pool.MaxOpenConns {
+		problem("database.pool.max_idle_conns (%d) must not exceed database.pool.max_open_conns (%d)", pool.MaxIdleConns, pool.MaxOpenConns)
+	}
+	nonNegative("database.pool.conn_max_lifetime", pool.ConnMaxLifetime)
+	nonNegative("database.pool.conn_max_idle_time", pool.ConnMaxIdleTime)
+	connect := c.Database.Connect
+	if connect.Timeout <= 0 {
+		problem("database.connect.timeout must be positive, got %s", connect.Timeout)
+	}
+	if connect.InitialBackoff <= 0 {
+		problem("database.connect.initial_backoff must be positive, got %s", connect.InitialBackoff)
+	}
+	if connect.MaxBackoff < connect.InitialBackoff {
+		problem("database.connect.max_backoff (%s) must not be less than database.connect.initial_backoff (%s)", connect.MaxBackoff, connect.InitialBackoff)
+	}
+
+	nonNegative("users.deleted_retention", c.Users.DeletedRetention)
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Logging.Level)); err != nil {
+		problem("logging.level must be one of debug, info, warn, error, got %q", c.Logging.Level)
+	}
+	oneOf("logging.format", c.Logging.Format, logFormats)
+
+	if _, err := c.GetAPIKeys(); err != nil {
+		problem("auth.api_keys: %v", err)
+	}
+	if jwt := c.Auth.JWT; jwt.JWKSFile != "" && (jwt.Issuer == "" || jwt.Audience == "") {
+		problem("auth.jwt.issuer and auth.jwt.audience are required along with auth.jwt.jwks_file")
+	}
+
+	for _, group := range slices.Sorted(maps.Keys(c.RateLimits)) {
+		limit := c.RateLimits[group]
+		if limit.Rate < 0 {
+			problem("rate_limits.%s.rate must not be negative, got %v", group, limit.Rate)
+		}
+		if limit.Rate > 0 && limit.Burst < 1 {
+			problem("rate_limits.%s.burst must be at least 1, got %d", group, limit.Burst)
+		}
+	}
+
+	oneOf("tracing.exporter", c.Tracing.Exporter, tracingExporters)
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problem("tracing.sample_ratio must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"cruder/internal/model"
+	"cruder/internal/service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyController struct {
+	service service.APIKeyService
+}
+
+func NewAPIKeyController(service service.APIKeyService) *APIKeyController {
+	return &APIKeyController{service: service}
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description The plaintext key is only part of this response, it cannot be retrieved later.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param key body model.CreateAPIKeyRequest true "API key"
+// @Success 201 {object} model.CreatedAPIKey
+// @Failure 400 {object} model.ErrorResponse "invalid request body"
+// @Failure 500 {object} model.ErrorResponse "internal server error"
+// @Security ApiKeyAuth || BearerAuth
+// @Router /api-keys [post]
+func (c *APIKeyController) CreateAPIKey(ctx *gin.Context) {
+	var req model.CreateAPIKeyRequest
+	if err := ctx.BindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	key, err := c.service.Create(ctx.Request.Context(), req)
+	if handleError(ctx, err) {
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, key)
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description Lists every key, revoked and expired ones included. Secrets are never returned.
+// @Tags api-keys
+// @Produce json
+// @Success 200 {array} model.APIKey
+// @Failure 500 {object} model.ErrorResponse "internal server error"
+// @Security ApiKeyAuth || BearerAuth
+// @Router /api-keys [get]
+func (c *APIKeyController) ListAPIKeys(ctx *gin.Context) {
+	keys, err := c.service.List(ctx.Request.Context())
+	if handleError(ctx, err) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Tags api-keys
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} model.APIKey
+// @Failure 400 {object} model.ErrorResponse "invalid id"
+// @Failure 404 {object} model.ErrorResponse "API key not found"
+// @Failure 500 {object} model.ErrorResponse "internal server error"
+// @Security ApiKeyAuth || BearerAuth
+// @Router /api-keys/{id} [delete]
+func (c *APIKeyController) RevokeAPIKey(ctx *gin.Context) {
+	id, ok := apiKeyID(ctx)
+	if !ok {
+		return
+	}
+
+	key, err := c.service.Revoke(ctx.Request.Context(), id)
+	if handleError(ctx, err) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, key)
+}
+
+// RotateAPIKey godoc
+// @Summary Rotate an API key
+// @Description Creates a new key with the same name, scopes and expiry. The old key keeps working for the overlap
+// @Description window, 24 hours unless given, so that clients can switch over. The plaintext key is only part of this response.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param id path int true "API key ID"
+// @Param rotation body model.RotateAPIKeyRequest false "Rotation"
+// @Success 201 {object} model.CreatedAPIKey
+// @Failure 400 {object} model.ErrorResponse "invalid request body"
+// @Failure 404 {object} model.ErrorResponse "API key not found"
+// @Failure 409 {object} model.ErrorResponse "API key is revoked, expired or already rotated"
+// @Failure 500 {object} model.ErrorResponse "internal server error"
+// @Security ApiKeyAuth || BearerAuth
+// @Router /api-keys/{id}/rotate [post]
+func (c *APIKeyController) RotateAPIKey(ctx *gin.Context) {
+	id, ok := apiKeyID(ctx)
+	if !ok {
+		return
+	}
+
+	var req model.RotateAPIKeyRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.BindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid request body"})
+			return
+		}
+	}
+	overlap := service.DefaultRotationOverlap
+	if req.Overlap != "" {
+		var err error
+		if overlap, err = time.ParseDuration(req.Overlap); err != nil {
+			handleError(ctx, service.ErrInvalidOverlap)
+			return
+		}
+	}
+
+	key, err := c.service.Rotate(ctx.Request.Context(), id, overlap)
+	if handleError(ctx, err) {
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, key)
+}
+
+func apiKeyID(ctx *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil || id < 1 {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid id"})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,114 @@
+package controller
+
+import (
+	mock_service "cruder/internal/mocks/service"
+	"cruder/internal/model"
+	"cruder/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func setupAPIKeyRouter(c *APIKeyController) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api-keys", c.ListAPIKeys)
+	r.POST("/api-keys", c.CreateAPIKey)
+	r.DELETE("/api-keys/:id", c.RevokeAPIKey)
+	r.POST("/api-keys/:id/rotate", c.RotateAPIKey)
+	return r
+}
+
+func TestCreateAPIKey_ShowsKeyOnce(t *testing.T) {
+	// Given: service creates a key
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockAPIKeyService(ctrl)
+	req := model.CreateAPIKeyRequest{Name: "crm", Scopes: []string{"users:read"}}
+	mockSvc.EXPECT().Create(gomock.Any(), req).Return(&model.CreatedAPIKey{
+		APIKey: model.APIKey{ID: 1, Name: "crm", Prefix: "cruder_000000000001", Salt: []byte("salt"), Hash: []byte("hash")},
+		Key:    "cruder_000000000001_secret",
+	}, nil)
+	router := setupAPIKeyRouter(NewAPIKeyController(mockSvc))
+
+	// When: POST /api-keys is called
+	w := httptest.NewRecorder()
+	httpReq, _ := http.NewRequest("POST", "/api-keys", strings.NewReader(`{"name":"crm","scopes":["users:read"]}`))
+	router.ServeHTTP(w, httpReq)
+
+	// Then: response should be 201 with the plaintext key but without its hash
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"key":"cruder_000000000001_secret"`)
+	assert.NotContains(t, w.Body.String(), "salt")
+	assert.NotContains(t, w.Body.String(), "hash")
+}
+
+func TestRotateAPIKey_Overlap(t *testing.T) {
+	tests := map[string]struct {
+		body    string
+		overlap time.Duration
+	}{
+		"default": {"", service.DefaultRotationOverlap},
+		"given":   {`{"overlap":"1h"}`, time.Hour},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Given: service rotates the key with the expected overlap
+			ctrl := gomock.NewController(t)
+			mockSvc := mock_service.NewMockAPIKeyService(ctrl)
+			mockSvc.EXPECT().Rotate(gomock.Any(), int64(1), tt.overlap).
+				Return(&model.CreatedAPIKey{APIKey: model.APIKey{ID: 2}, Key: "new"}, nil)
+			router := setupAPIKeyRouter(NewAPIKeyController(mockSvc))
+
+			// When: POST /api-keys/1/rotate is called
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/api-keys/1/rotate", strings.NewReader(tt.body))
+			router.ServeHTTP(w, req)
+
+			// Then: response should be 201 with the new key
+			assert.Equal(t, http.StatusCreated, w.Code)
+			assert.Contains(t, w.Body.String(), `"key":"new"`)
+		})
+	}
+}
+
+func TestRotateAPIKey_InvalidOverlap(t *testing.T) {
+	// Given: an overlap that is not a duration
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockAPIKeyService(ctrl)
+	router := setupAPIKeyRouter(NewAPIKeyController(mockSvc))
+
+	// When: POST /api-keys/1/rotate is called
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/api-keys/1/rotate", strings.NewReader(`{"overlap":"a day"}`))
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 400 with error
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid overlap")
+}
+
+func TestRevokeAPIKey_NotFound(t *testing.T) {
+	// Given: service does not know the key
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockAPIKeyService(ctrl)
+	mockSvc.EXPECT().Revoke(gomock.Any(), int64(9)).Return(nil, service.ErrAPIKeyNotFound)
+	router := setupAPIKeyRouter(NewAPIKeyController(mockSvc))
+
+	// When: DELETE /api-keys/9 is called
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/api-keys/9", nil)
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 404 with error
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "API key not found")
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"cruder/internal/model"
+	"cruder/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditController struct {
+	service   service.AuditService
+	users     service.UserService
+	legacyIDs bool
+}
+
+func NewAuditController(service service.AuditService, users service.UserService, legacyIDs bool) *AuditController {
+	return &AuditController{service: service, users: users, legacyIDs: legacyIDs}
+}
+
+// ListAudit godoc
+// @Summary List audit entries
+// @Description Lists changes to users, newest first, with before and after snapshots of the user.
+// @Tags audit
+// @Produce json
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "Opaque cursor from a previous page"
+// @Param user_uuid query string false "Only changes to the user with this UUID"
+// @Param actor query string false "Only changes made by this actor"
+// @Param action query string false "Only changes of this kind" Enums(create, update, delete, restore, purge)
+// @Param since query string false "Only changes made after this RFC 3339 time"
+// @Param until query string false "Only changes made before this RFC 3339 time"
+// @Success 200 {object} model.AuditPage
+// @Header 200 {string} Link "Link to the next page, rel=next"
+// @Failure 400 {object} model.ErrorResponse "invalid query parameters"
+// @Failure 500 {object} model.ErrorResponse "internal server error"
+// @Security ApiKeyAuth || BearerAuth
+// @Router /audit [get]
+func (c *AuditController) ListAudit(ctx *gin.Context) {
+	var params model.AuditListParams
+	if err := ctx.ShouldBindQuery(&params); err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid query parameters"})
+		return
+	}
+
+	c.list(ctx, params)
+}
+
+// GetUserAudit godoc
+// @Summary List audit entries of a user
+// @Description Lists changes to a single user, newest first. Deleted users are included.
+// @Tags audit
+// @Produce json
+// @Param id path string true "User UUID, or integer ID while legacy ID routes are enabled"
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "Opaque cursor from a previous page"
+// @Param actor query string false "Only changes made by this actor"
+// @Param action query string false "Only changes of this kind" Enums(create, update, delete, restore, purge)
+// @Param since query string false "Only changes made after this RFC 3339 time"
+// @Param until query string false "Only changes made before this RFC 3339 time"
+// @Success 200 {object} model.AuditPage
+// @Header 200 {string} Link "Link to the next page, rel=next"
+// @Failure 400 {object} model.ErrorResponse "invalid query parameters"
+// @Failure 404 {object} model.ErrorResponse "user not found"
+// @Failure 500 {object} model.ErrorResponse "internal server error"
+// @Security ApiKeyAuth || BearerAuth
+// @Router /users/{id}/audit [get]
+func (c *AuditController) GetUserAudit(ctx *gin.Context) {
+	var params model.AuditListParams
+	if err := ctx.ShouldBindQuery(&params); err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid query parameters"})
+		return
+	}
+
+	id, ok := resolveUserID(ctx, c.users, c.legacyIDs)
+	if !ok {
+		return
+	}
+	params.UserID = id
+
+	c.list(ctx, params)
+}
+
+func (c *AuditController) list(ctx *gin.Context, params model.AuditListParams) {
+	page, err := c.service.List(ctx.Request.Context(), params)
+	if handleError(ctx, err) {
+		return
+	}
+
+	if page.NextCursor != "" {
+		ctx.Header("Link", nextPageLink(ctx.Request.URL, page.NextCursor))
+	}
+	ctx.JSON(http.StatusOK, page)
+}
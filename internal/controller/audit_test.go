@@ -0,0 +1,92 @@
+package controller
+
+import (
+	mock_service "cruder/internal/mocks/service"
+	"cruder/internal/model"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func setupAuditRouter(c *AuditController) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/audit", c.ListAudit)
+	r.GET("/users/:id/audit", c.GetUserAudit)
+	return r
+}
+
+func TestListAudit_Filters(t *testing.T) {
+	// Given: service returns a page of audit entries followed by another page
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockAuditService(ctrl)
+	page := &model.AuditPage{
+		Entries:    []model.UserAudit{{ID: 3, UserID: 1, Actor: "crm", Action: model.AuditDelete, Before: json.RawMessage(`{"id":1}`)}},
+		NextCursor: "next",
+	}
+	params := model.AuditListParams{Limit: 1, UserUUID: "123e4567-e89b-12d3-a456-426614174000", Actor: "crm", Action: model.AuditDelete}
+	mockSvc.EXPECT().List(gomock.Any(), params).Return(page, nil)
+
+	controller := NewAuditController(mockSvc, mock_service.NewMockUserService(ctrl), true)
+	router := setupAuditRouter(controller)
+
+	// When: GET /audit is called with filters
+	req, _ := http.NewRequest("GET", "/audit?limit=1&user_uuid=123e4567-e89b-12d3-a456-426614174000&actor=crm&action=delete", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should hold the entries, with a null after snapshot, and a Link header
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"before":{"id":1},"after":null`)
+	assert.Contains(t, w.Header().Get("Link"), "cursor=next")
+}
+
+func TestListAudit_InvalidUserUUID(t *testing.T) {
+	// Given: a user_uuid that is not a UUID
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockAuditService(ctrl)
+
+	controller := NewAuditController(mockSvc, mock_service.NewMockUserService(ctrl), true)
+	router := setupAuditRouter(controller)
+
+	// When: GET /audit?user_uuid=1 is called
+	req, _ := http.NewRequest("GET", "/audit?user_uuid=1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 400 with error
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetUserAudit_ResolvesUUID(t *testing.T) {
+	// Given: a deleted user is resolved by UUID and has audit entries
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockAuditService(ctrl)
+	mockUsers := mock_service.NewMockUserService(ctrl)
+	uuid := "123e4567-e89b-12d3-a456-426614174000"
+	mockUsers.EXPECT().GetByUUID(gomock.Any(), uuid, true).Return(&model.User{ID: 5, UUID: uuid}, nil)
+	mockSvc.EXPECT().List(gomock.Any(), model.AuditListParams{UserID: 5}).
+		Return(&model.AuditPage{Entries: []model.UserAudit{{ID: 1, UserID: 5}}}, nil)
+
+	controller := NewAuditController(mockSvc, mockUsers, true)
+	router := setupAuditRouter(controller)
+
+	// When: GET /users/{uuid}/audit is called
+	req, _ := http.NewRequest("GET", "/users/"+uuid+"/audit", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: the entries of the resolved user should be returned
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got model.AuditPage
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	assert.Equal(t, int64(5), got.Entries[0].UserID)
+}
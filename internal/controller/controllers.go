@@ -1,15 +1,24 @@
 package controller
 
-import "cruder/internal/service"
+import (
+	"cruder/internal/service"
+	"log/slog"
+)
 
 type Controller struct {
-	Users  *UserController
-	Health *HealthController
+	Users   *UserController
+	Audit   *AuditController
+	APIKeys *APIKeyController
+	Health  *HealthController
 }
 
-func NewController(services *service.Service) *Controller {
+// NewController creates the controllers. Readiness checks each of the
+// readiness components, logging their failures to logger.
+func NewController(services *service.Service, legacyIDRoutes bool, readiness map[string]Pinger, logger *slog.Logger) *Controller {
 	return &Controller{
-		Users:  NewUserController(services.Users),
-		Health: NewHealthController(),
+		Users:   NewUserController(services.Users, legacyIDRoutes),
+		Audit:   NewAuditController(services.Audit, services.Users, legacyIDRoutes),
+		APIKeys: NewAPIKeyController(services.APIKeys),
+		Health:  NewHealthController(readiness, logger),
 	}
 }
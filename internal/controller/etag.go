@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"cruder/internal/model"
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setETag exposes the user's version as a strong entity tag.
+func setETag(ctx *gin.Context, user *model.User) {
+	ctx.Header("ETag", fmt.Sprintf(`"%d"`, user.Version))
+}
+
+// ifMatchVersion reads the expected user version from the If-Match header.
+// A missing header or "*" yields 0, which skips the version check. On failure
+// the error response has already been written.
+func ifMatchVersion(ctx *gin.Context) (int64, bool) {
+	header := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, true
+	}
+
+	// If-Match uses strong comparison, so a weak tag can never match.
+	if strings.HasPrefix(header, "W/") {
+		handleError(ctx, service.ErrPreconditionFailed)
+		return 0, false
+	}
+	if unquoted, err := strconv.Unquote(header); err == nil {
+		if version, err := strconv.ParseInt(unquoted, 10, 64); err == nil && version > 0 {
+			return version, true
+		}
+	}
+
+	ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "If-Match must be * or a single ETag returned by this API"})
+	return 0, false
+}
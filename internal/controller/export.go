@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"cruder/internal/model"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userWriter writes exported users to the response one at a time.
+type userWriter interface {
+	Write(u *model.User) error
+	Flush() error
+}
+
+var exportContentTypes = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"ndjson": "application/x-ndjson",
+}
+
+// ExportUsers godoc
+// @Summary Export users
+// @Description Streams every user matching the filters as CSV or newline delimited JSON. Paging parameters are ignored.
+// @Tags users
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format query string true "Export format" Enums(csv, ndjson)
+// @Param username query string false "Exact username"
+// @Param username_prefix query string false "Username prefix"
+// @Param email_domain query string false "Exact email domain, case insensitive"
+// @Param email_domain_prefix query string false "Email domain prefix, case insensitive"
+// @Param full_name query string false "Exact full name"
+// @Param full_name_prefix query string false "Full name prefix"
+// @Param created_after query string false "Only users created after this RFC 3339 time"
+// @Param updated_after query string false "Only users updated after this RFC 3339 time"
+// @Param include_deleted query bool false "Also export soft deleted users"
+// @Param sort query string false "Comma separated sort fields (id, username, email, full_name, created_at, updated_at), prefix with - for descending"
+// @Success 200 {string} string "users"
+// @Failure 400 {object} model.ErrorResponse "invalid query parameters"
+// @Failure 500 {object} model.ErrorResponse "internal server error"
+// @Security ApiKeyAuth || BearerAuth
+// @Router /users/export [get]
+func (c *UserController) ExportUsers(ctx *gin.Context) {
+	var params model.UserListParams
+	if err := ctx.ShouldBindQuery(&params); err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid query parameters"})
+		return
+	}
+
+	format := ctx.Query("format")
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid export format (csv or ndjson)"})
+		return
+	}
+
+	// An export can take longer than the server's write timeout allows.
+	_ = http.NewResponseController(ctx.Writer).SetWriteDeadline(time.Time{})
+
+	// The response is only committed once the first user arrives, so that
+	// errors raised before the export starts still get a proper status.
+	var w userWriter
+	start := func() {
+		ctx.Header("Content-Type", contentType)
+		ctx.Header("Content-Disposition", `attachment; filename="users.`+format+`"`)
+		ctx.Status(http.StatusOK)
+		if format == "csv" {
+			w = newCSVUserWriter(ctx.Writer)
+		} else {
+			w = newNDJSONUserWriter(ctx.Writer)
+		}
+	}
+
+	err := c.service.Export(ctx.Request.Context(), params, func(u *model.User) error {
+		if w == nil {
+			start()
+		}
+		return w.Write(u)
+	})
+	if w == nil {
+		if handleError(ctx, err) {
+			return
+		}
+		start()
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		// The status has been sent already, all that is left is to cut the
+		// export short and record why.
+		_ = ctx.Error(err)
+		ctx.Abort()
+	}
+}
+
+var userCSVHeader = []string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at"}
+
+type csvUserWriter struct {
+	w *csv.Writer
+}
+
+// newCSVUserWriter writes the header row right away, so that an empty export
+// still describes its columns.
+func newCSVUserWriter(out io.Writer) *csvUserWriter {
+	w := csv.NewWriter(out)
+	_ = w.Write(userCSVHeader)
+	return &csvUserWriter{w: w}
+}
+
+func (cw *csvUserWriter) Write(u *model.User) error {
+	deletedAt := ""
+	if u.DeletedAt != nil {
+		deletedAt = u.DeletedAt.Format(time.RFC3339Nano)
+	}
+	return cw.w.Write([]string{
+		strconv.FormatInt(u.ID, 10),
+		u.UUID,
+		u.Username,
+		u.Email,
+		u.FullName,
+		u.CreatedAt.Format(time.RFC3339Nano),
+		u.UpdatedAt.Format(time.RFC3339Nano),
+		deletedAt,
+	})
+}
+
+func (cw *csvUserWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+type ndjsonUserWriter struct {
+	enc *json.Encoder
+}
+
+func newNDJSONUserWriter(out io.Writer) *ndjsonUserWriter {
+	return &ndjsonUserWriter{enc: json.NewEncoder(out)}
+}
+
+// Write encodes u on a line of its own.
+func (nw *ndjsonUserWriter) Write(u *model.User) error {
+	return nw.enc.Encode(u)
+}
+
+func (nw *ndjsonUserWriter) Flush() error {
+	return nil
+}
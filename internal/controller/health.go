@@ -1,13 +1,122 @@
 package controller
 
-import "github.com/gin-gonic/gin"
+import (
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/version"
+	"errors"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 
-type HealthController struct{}
+	"github.com/gin-gonic/gin"
+)
 
-func NewHealthController() *HealthController {
-	return &HealthController{}
+// readinessTimeout bounds how long each component may take to answer a
+// readiness check.
+const readinessTimeout = 2 * time.Second
+
+// Pinger is a dependency the service needs to serve requests, such as the
+// database.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+type HealthController struct {
+	components map[string]Pinger
+	timeout    time.Duration
+	draining   atomic.Bool
+	logger     *slog.Logger
+}
+
+// NewHealthController creates the health controller. Readiness checks each of
+// components, keyed by the name they are reported under, and logs why a
+// component failed.
+func NewHealthController(components map[string]Pinger, logger *slog.Logger) *HealthController {
+	return &HealthController{components: components, timeout: readinessTimeout, logger: logger}
 }
 
+// HealthCheck reports that the process is alive. It does not look at any
+// dependency, so that a database outage does not get every instance restarted.
 func (c *HealthController) HealthCheck(ctx *gin.Context) {
 	ctx.JSON(200, gin.H{"status": "ok"})
 }
+
+// Drain makes readiness fail from now on, so that no new requests are routed
+// to a service that is shutting down.
+func (c *HealthController) Drain() {
+	c.draining.Store(true)
+}
+
+// Readiness reports whether every component answers in time, along with how
+// long each of them took. It responds with 503 when one does not, or once the
+// service is draining.
+func (c *HealthController) Readiness(ctx *gin.Context) {
+	if c.draining.Load() {
+		ctx.JSON(http.StatusServiceUnavailable, model.ReadinessResponse{
+			Status:     model.HealthDraining,
+			Components: map[string]model.ComponentHealth{},
+		})
+		return
+	}
+
+	resp := model.ReadinessResponse{
+		Status:     model.HealthOK,
+		Components: make(map[string]model.ComponentHealth, len(c.components)),
+	}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for name, component := range c.components {
+		wg.Go(func() {
+			health := c.check(ctx.Request.Context(), name, component)
+			mu.Lock()
+			defer mu.Unlock()
+			resp.Components[name] = health
+			if health.Status != model.HealthOK {
+				resp.Status = model.HealthUnavailable
+			}
+		})
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	if resp.Status != model.HealthOK {
+		status = http.StatusServiceUnavailable
+	}
+	ctx.JSON(status, resp)
+}
+
+func (c *HealthController) check(ctx context.Context, name string, component Pinger) model.ComponentHealth {
+	pingCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := component.PingContext(pingCtx)
+	health := model.ComponentHealth{
+		Status:    model.HealthOK,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		c.logger.WarnContext(ctx, "readiness check failed", slog.String("component", name), slog.Any("err", err))
+		health.Status = model.HealthUnavailable
+		health.Error = model.HealthErrorUnreachable
+		if errors.Is(err, context.DeadlineExceeded) {
+			health.Error = model.HealthErrorTimeout
+		}
+	}
+	return health
+}
+
+// Version reports the build the service is running.
+func (c *HealthController) Version(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, model.VersionResponse{
+		Version:   version.Version,
+		Commit:    version.Commit,
+		GoVersion: runtime.Version(),
+	})
+}
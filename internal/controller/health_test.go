@@ -1,23 +1,35 @@
 package controller
 
 import (
+	"bytes"
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/version"
+	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
 
+var discardLogger = slog.New(slog.DiscardHandler)
+
 func setupHealthRouter(c *HealthController) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
 	r.GET("/healthz", c.HealthCheck)
+	r.GET("/readyz", c.Readiness)
+	r.GET("/version", c.Version)
 	return r
 }
 
 func TestHealthCheck_Success(t *testing.T) {
-	controller := NewHealthController()
+	controller := NewHealthController(nil, discardLogger)
 	router := setupHealthRouter(controller)
 
 	req, err := http.NewRequest("GET", "/healthz", nil)
@@ -27,3 +39,108 @@ func TestHealthCheck_Success(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, w.Code)
 }
+
+// pingerFunc turns a function into a Pinger.
+type pingerFunc func(ctx context.Context) error
+
+func (f pingerFunc) PingContext(ctx context.Context) error {
+	return f(ctx)
+}
+
+func TestReadiness_AllComponentsOK(t *testing.T) {
+	// Given: a database that answers
+	controller := NewHealthController(map[string]Pinger{
+		"database": pingerFunc(func(context.Context) error { return nil }),
+	}, discardLogger)
+	router := setupHealthRouter(controller)
+
+	// When: readiness is checked
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: the service and the database should be reported ok
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp model.ReadinessResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, model.HealthOK, resp.Status)
+	assert.Equal(t, model.HealthOK, resp.Components["database"].Status)
+	assert.Empty(t, resp.Components["database"].Error)
+}
+
+func TestReadiness_ComponentTimesOut(t *testing.T) {
+	// Given: a database that hangs and a cache that fails
+	var logs bytes.Buffer
+	controller := NewHealthController(map[string]Pinger{
+		"database": pingerFunc(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+		"cache": pingerFunc(func(context.Context) error { return errors.New("connection refused") }),
+	}, slog.New(slog.NewTextHandler(&logs, nil)))
+	controller.timeout = 20 * time.Millisecond
+	router := setupHealthRouter(controller)
+
+	// When: readiness is checked
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: the service should be unavailable, with each failure reported
+	// without its details, which are only logged
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var resp model.ReadinessResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, model.HealthUnavailable, resp.Status)
+	database := resp.Components["database"]
+	assert.Equal(t, model.HealthUnavailable, database.Status)
+	assert.Equal(t, model.HealthErrorTimeout, database.Error)
+	assert.GreaterOrEqual(t, database.LatencyMS, float64(20))
+	assert.Equal(t, model.HealthErrorUnreachable, resp.Components["cache"].Error)
+	assert.NotContains(t, w.Body.String(), "connection refused")
+	assert.Contains(t, logs.String(), `component=cache err="connection refused"`)
+}
+
+func TestVersion(t *testing.T) {
+	// Given: a build with an injected version and commit
+	defer func(v, c string) { version.Version, version.Commit = v, c }(version.Version, version.Commit)
+	version.Version, version.Commit = "v1.2.0", "0dadb51"
+	router := setupHealthRouter(NewHealthController(nil, discardLogger))
+
+	// When: the version is requested
+	req, _ := http.NewRequest("GET", "/version", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: both should be reported
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp model.VersionResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "v1.2.0", resp.Version)
+	assert.Equal(t, "0dadb51", resp.Commit)
+	assert.NotEmpty(t, resp.GoVersion)
+}
+
+func TestReadiness_Draining(t *testing.T) {
+	// Given: a healthy database and a service that started shutting down
+	controller := NewHealthController(map[string]Pinger{
+		"database": pingerFunc(func(context.Context) error { return nil }),
+	}, discardLogger)
+	controller.Drain()
+	router := setupHealthRouter(controller)
+
+	// When: readiness and liveness are checked
+	req, _ := http.NewRequest("GET", "/readyz", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	liveReq, _ := http.NewRequest("GET", "/healthz", nil)
+	live := httptest.NewRecorder()
+	router.ServeHTTP(live, liveReq)
+
+	// Then: the service should no longer be ready, but still alive
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	var resp model.ReadinessResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, model.HealthDraining, resp.Status)
+	assert.Equal(t, http.StatusOK, live.Code)
+}
@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"cruder/internal/model"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportBytes caps the size of an uploaded CSV file.
+const maxImportBytes = 10 << 20
+
+var importColumns = []string{"username", "email", "full_name"}
+
+// ImportUsers godoc
+// @Summary Import users from CSV
+// @Description The CSV needs a header row with the username, email and full_name columns, in any order.
+// @Description Every row is validated and checked for uniqueness. Rows that pass are created in a single transaction,
+// @Description a dry run only reports them. The CSV is either the request body or the file field of a multipart form.
+// @Tags users
+// @Accept text/csv
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file false "CSV file"
+// @Param dry_run query bool false "Only validate the rows"
+// @Success 200 {object} model.ImportReport
+// @Failure 400 {object} model.ErrorResponse "invalid CSV"
+// @Failure 413 {object} model.ErrorResponse "CSV too large"
+// @Failure 500 {object} model.ErrorResponse "internal server error"
+// @Security ApiKeyAuth || BearerAuth
+// @Router /users/import [post]
+func (c *UserController) ImportUsers(ctx *gin.Context) {
+	var query struct {
+		DryRun bool `form:"dry_run"`
+	}
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid query parameters"})
+		return
+	}
+
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxImportBytes)
+	var body io.Reader = ctx.Request.Body
+	if strings.HasPrefix(ctx.ContentType(), "multipart/form-data") {
+		header, err := ctx.FormFile("file")
+		if importTooLarge(ctx, err) {
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "missing CSV file"})
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "missing CSV file"})
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	users, lines, err := readUsersCSV(body)
+	if importTooLarge(ctx, err) {
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	results, err := c.service.Import(ctx.Request.Context(), users, query.DryRun)
+	if handleError(ctx, err) {
+		return
+	}
+
+	report := model.ImportReport{DryRun: query.DryRun, Lines: make([]model.ImportLine, len(results))}
+	for i, r := range results {
+		line := model.ImportLine{Line: lines[i], Username: users[i].Username}
+		switch {
+		case r.Err != nil:
+			_, resp := errorResponse(r.Err)
+			line.Status, line.Error = model.ImportFailed, resp.Error
+			report.Failed++
+		case query.DryRun:
+			line.Status = model.ImportValid
+			report.Valid++
+		default:
+			line.Status, line.User = model.ImportCreated, r.User
+			report.Valid++
+		}
+		report.Lines[i] = line
+	}
+	ctx.JSON(http.StatusOK, report)
+}
+
+// importTooLarge answers 413 and returns true if err comes from reading past
+// maxImportBytes.
+func importTooLarge(ctx *gin.Context, err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(err, &maxBytesErr) {
+		return false
+	}
+	ctx.JSON(http.StatusRequestEntityTooLarge, model.ErrorResponse{Error: fmt.Sprintf("CSV must not be larger than %d bytes", maxImportBytes)})
+	return true
+}
+
+// readUsersCSV reads users from a CSV with a header row and returns them along
+// with the line each of them starts on.
+func readUsersCSV(r io.Reader) ([]model.User, []int, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return nil, nil, err
+	}
+	if err != nil {
+		return nil, nil, errors.New("invalid CSV: missing header row")
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// Spreadsheet exports tend to start with a byte order mark.
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[name] = i
+	}
+	for _, name := range importColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, nil, fmt.Errorf("invalid CSV: header is missing the %s column", name)
+		}
+	}
+
+	var (
+		users []model.User
+		lines []int
+	)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		users = append(users, model.User{
+			Username: record[columns["username"]],
+			Email:    record[columns["email"]],
+			FullName: record[columns["full_name"]],
+		})
+		lines = append(lines, line)
+	}
+	return users, lines, nil
+}
@@ -1,7 +1,12 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strconv"
 
 	"cruder/internal/model"
@@ -11,28 +16,82 @@ import (
 )
 
 type UserController struct {
-	service service.UserService
+	service   service.UserService
+	legacyIDs bool
 }
 
-func NewUserController(service service.UserService) *UserController {
-	return &UserController{service: service}
+// NewUserController creates the user controller. With legacyIDs set, the
+// :id path parameter also accepts integer IDs next to UUIDs.
+func NewUserController(service service.UserService, legacyIDs bool) *UserController {
+	return &UserController{service: service, legacyIDs: legacyIDs}
 }
 
 // GetAllUsers godoc
 // @Summary Get all users
+// @Description Returns users one page at a time. Follow next_cursor or the Link header to fetch the next page.
 // @Tags users
 // @Produce json
-// @Success 200 {array} model.User
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "Opaque cursor from a previous page"
+// @Param username query string false "Exact username"
+// @Param username_prefix query string false "Username prefix"
+// @Param email_domain query string false "Exact email domain, case insensitive"
+// @Param email_domain_prefix query string false "Email domain prefix, case insensitive"
+// @Param full_name query string false "Exact full name"
+// @Param full_name_prefix query string false "Full name prefix"
+// @Param created_after query string false "Only users created after this RFC 3339 time"
+// @Param updated_after query string false "Only users updated after this RFC 3339 time"
+// @Param include_deleted query bool false "Also list soft deleted users"
+// @Param sort query string false "Comma separated sort fields (id, username, email, full_name, created_at, updated_at), prefix with - for descending, e.g. username,-id"
+// @Success 200 {object} model.UserPage
+// @Header 200 {string} Link "RFC 8288 link to the next page"
+// @Failure 400 {object} model.ErrorResponse "invalid query parameters"
 // @Failure 500 {object} model.ErrorResponse "internal server error"
-// @Security ApiKeyAuth
+// @Security ApiKeyAuth || BearerAuth
 // @Router /users/ [get]
 func (c *UserController) GetAllUsers(ctx *gin.Context) {
-	users, err := c.service.GetAll()
+	var params model.UserListParams
+	if err := ctx.ShouldBindQuery(&params); err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid query parameters"})
+		return
+	}
+
+	page, err := c.service.GetAll(ctx.Request.Context(), params)
+	if handleError(ctx, err) {
+		return
+	}
+
+	if page.NextCursor != "" {
+		ctx.Header("Link", nextPageLink(ctx.Request.URL, page.NextCursor))
+	}
+	ctx.JSON(http.StatusOK, page)
+}
+
+// SearchUsers godoc
+// @Summary Search users
+// @Description Fuzzy matches the query against username, email and full name, best matches first. Deleted users are not searched.
+// @Tags users
+// @Produce json
+// @Param q query string true "Search query, e.g. part of a name"
+// @Param limit query int false "Maximum number of results (default 20, max 500)"
+// @Success 200 {object} model.UserSearchResult
+// @Failure 400 {object} model.ErrorResponse "invalid query parameters"
+// @Failure 500 {object} model.ErrorResponse "internal server error"
+// @Security ApiKeyAuth || BearerAuth
+// @Router /users/search [get]
+func (c *UserController) SearchUsers(ctx *gin.Context) {
+	var params model.UserSearchParams
+	if err := ctx.ShouldBindQuery(&params); err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid query parameters"})
+		return
+	}
+
+	result, err := c.service.Search(ctx.Request.Context(), params)
 	if handleError(ctx, err) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, users)
+	ctx.JSON(http.StatusOK, result)
 }
 
 // GetUserByUsername godoc
@@ -40,32 +99,80 @@ func (c *UserController) GetAllUsers(ctx *gin.Context) {
 // @Tags users
 // @Produce json
 // @Param username path string true "Username"
+// @Param include_deleted query bool false "Also return the user when it is soft deleted"
 // @Success 200 {object} model.User
+// @Header 200 {string} ETag "User version, send back in If-Match"
 // @Failure 404 {object} model.ErrorResponse "user not found"
 // @Failure 500 {object} model.ErrorResponse "internal server error"
-// @Security ApiKeyAuth
+// @Security ApiKeyAuth || BearerAuth
 // @Router /users/username/{username} [get]
 func (c *UserController) GetUserByUsername(ctx *gin.Context) {
 	username := ctx.Param("username")
+	includeDeleted, ok := includeDeletedParam(ctx)
+	if !ok {
+		return
+	}
 
-	user, err := c.service.GetByUsername(username)
+	user, err := c.service.GetByUsername(ctx.Request.Context(), username, includeDeleted)
 	if handleError(ctx, err) {
 		return
 	}
 
+	setETag(ctx, user)
+	ctx.JSON(http.StatusOK, user)
+}
+
+// GetUser godoc
+// @Summary Get user by UUID
+// @Tags users
+// @Produce json
+// @Param id path string true "User UUID, or integer ID while legacy ID routes are enabled"
+// @Param include_deleted query bool false "Also return the user when it is soft deleted"
+// @Success 200 {object} model.User
+// @Header 200 {string} ETag "User version, send back in If-Match"
+// @Failure 400 {object} model.ErrorResponse "invalid id"
+// @Failure 404 {object} model.ErrorResponse "user not found"
+// @Failure 500 {object} model.ErrorResponse "internal server error"
+// @Security ApiKeyAuth || BearerAuth
+// @Router /users/{id} [get]
+func (c *UserController) GetUser(ctx *gin.Context) {
+	var user *model.User
+	var err error
+
+	includeDeleted, ok := includeDeletedParam(ctx)
+	if !ok {
+		return
+	}
+	if param := ctx.Param("id"); uuidRegex.MatchString(param) {
+		user, err = c.service.GetByUUID(ctx.Request.Context(), param, includeDeleted)
+	} else {
+		id, ok := c.userID(ctx)
+		if !ok {
+			return
+		}
+		user, err = c.service.GetByID(ctx.Request.Context(), id, includeDeleted)
+	}
+	if handleError(ctx, err) {
+		return
+	}
+
+	setETag(ctx, user)
 	ctx.JSON(http.StatusOK, user)
 }
 
 // GetUserByID godoc
 // @Summary Get user by ID
+// @Description Legacy route, only available while legacy ID routes are enabled.
 // @Tags users
 // @Produce json
 // @Param id path int true "User ID"
+// @Param include_deleted query bool false "Also return the user when it is soft deleted"
 // @Success 200 {object} model.User
+// @Header 200 {string} ETag "User version, send back in If-Match"
 // @Failure 400 {object} model.ErrorResponse "invalid id"
 // @Failure 404 {object} model.ErrorResponse "user not found"
 // @Failure 500 {object} model.ErrorResponse "internal server error"
-// @Security ApiKeyAuth
+// @Security ApiKeyAuth || BearerAuth
 // @Router /users/id/{id} [get]
 func (c *UserController) GetUserByID(ctx *gin.Context) {
 	idStr := ctx.Param("id")
@@ -74,12 +181,17 @@ func (c *UserController) GetUserByID(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid id"})
 		return
 	}
+	includeDeleted, ok := includeDeletedParam(ctx)
+	if !ok {
+		return
+	}
 
-	user, err := c.service.GetByID(id)
+	user, err := c.service.GetByID(ctx.Request.Context(), id, includeDeleted)
 	if handleError(ctx, err) {
 		return
 	}
 
+	setETag(ctx, user)
 	ctx.JSON(http.StatusOK, user)
 }
 
@@ -90,11 +202,12 @@ func (c *UserController) GetUserByID(ctx *gin.Context) {
 // @Produce json
 // @Param user body model.User true "User"
 // @Success 201 {object} model.User
+// @Header 201 {string} ETag "User version, send back in If-Match"
 // @Failure 400 {object} model.ErrorResponse "invalid request body"
 // @Failure 409 {object} model.ErrorResponse "user already exists"
 // @Failure 409 {object} model.ErrorResponse "user with username/email already exists"
 // @Failure 500 {object} model.ErrorResponse "internal server error"
-// @Security ApiKeyAuth
+// @Security ApiKeyAuth || BearerAuth
 // @Router /users/ [post]
 func (c *UserController) CreateUser(ctx *gin.Context) {
 	var user model.User
@@ -104,31 +217,86 @@ func (c *UserController) CreateUser(ctx *gin.Context) {
 		return
 	}
 
-	createdUser, err := c.service.Create(&user)
+	createdUser, err := c.service.Create(ctx.Request.Context(), &user)
 	if handleError(ctx, err) {
 		return
 	}
 
+	setETag(ctx, createdUser)
 	ctx.JSON(http.StatusCreated, createdUser)
 }
 
+// CreateUsersBulk godoc
+// @Summary Create many users at once
+// @Description Every user is validated and created on its own and gets its own status in the response.
+// @Description In atomic mode the users are created in one transaction, so a single failure means none are created.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param users body []model.User true "Users"
+// @Param atomic query bool false "Create all users or none"
+// @Success 207 {object} model.BulkCreateResponse
+// @Failure 400 {object} model.ErrorResponse "invalid request body"
+// @Failure 500 {object} model.ErrorResponse "internal server error"
+// @Security ApiKeyAuth || BearerAuth
+// @Router /users/bulk [post]
+func (c *UserController) CreateUsersBulk(ctx *gin.Context) {
+	var users []model.User
+	if err := ctx.BindJSON(&users); err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	var query struct {
+		Atomic bool `form:"atomic"`
+	}
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid query parameters"})
+		return
+	}
+
+	results, err := c.service.CreateBulk(ctx.Request.Context(), users, query.Atomic)
+	if handleError(ctx, err) {
+		return
+	}
+
+	resp := model.BulkCreateResponse{Results: make([]model.BulkCreateResult, len(results))}
+	for i, r := range results {
+		if r.Err != nil {
+			status, body := errorResponse(r.Err)
+			resp.Results[i] = model.BulkCreateResult{Status: status, Error: body.Error}
+			resp.Failed++
+			continue
+		}
+		resp.Results[i] = model.BulkCreateResult{Status: http.StatusCreated, User: r.User}
+		resp.Created++
+	}
+	ctx.JSON(http.StatusMultiStatus, resp)
+}
+
 // DeleteUser godoc
-// @Summary Delete user by ID
+// @Summary Delete user by UUID
+// @Description Soft deletes the user. It can be restored until it is purged.
 // @Tags users
-// @Param id path int true "User ID"
+// @Param id path string true "User UUID, or integer ID while legacy ID routes are enabled"
+// @Param If-Match header string false "ETag from a previous response, the write fails with 412 when it is stale"
 // @Success 204
 // @Failure 400 {object} model.ErrorResponse "invalid id"
 // @Failure 404 {object} model.ErrorResponse "user not found"
+// @Failure 412 {object} model.ErrorResponse "user has been modified"
 // @Failure 500 {object} model.ErrorResponse "internal server error"
-// @Security ApiKeyAuth
+// @Security ApiKeyAuth || BearerAuth
 // @Router /users/{id} [delete]
 func (c *UserController) DeleteUser(ctx *gin.Context) {
-	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid id"})
+	version, ok := ifMatchVersion(ctx)
+	if !ok {
+		return
+	}
+	id, ok := c.userID(ctx)
+	if !ok {
 		return
 	}
-	err = c.service.Delete(id)
+	err := c.service.Delete(ctx.Request.Context(), id, version)
 
 	if handleError(ctx, err) {
 		return
@@ -138,55 +306,239 @@ func (c *UserController) DeleteUser(ctx *gin.Context) {
 }
 
 // UpdateUser godoc
-// @Summary Update user by ID
+// @Summary Update user by UUID
 // @Tags users
 // @Accept json
 // @Produce json
-// @Param id path int true "User ID"
+// @Param id path string true "User UUID, or integer ID while legacy ID routes are enabled"
+// @Param If-Match header string false "ETag from a previous response, the write fails with 412 when it is stale"
 // @Param user body model.User true "User"
 // @Success 200 {object} model.User
+// @Header 200 {string} ETag "User version, send back in If-Match"
 // @Failure 400 {object} model.ErrorResponse "invalid id or body mismatch"
 // @Failure 404 {object} model.ErrorResponse "user not found"
 // @Failure 409 {object} model.ErrorResponse "user with username/email already exists"
+// @Failure 412 {object} model.ErrorResponse "user has been modified"
 // @Failure 500 {object} model.ErrorResponse "internal server error"
-// @Security ApiKeyAuth
+// @Security ApiKeyAuth || BearerAuth
 // @Router /users/{id} [put]
 func (c *UserController) UpdateUser(ctx *gin.Context) {
 	var user model.User
-	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid id"})
-		return
-	}
 	if err := ctx.BindJSON(&user); err != nil {
 		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid request body"})
 		return
 	}
-	if id != user.ID {
+	version, ok := ifMatchVersion(ctx)
+	if !ok {
+		return
+	}
+	id, ok := c.userID(ctx)
+	if !ok {
+		return
+	}
+	if user.ID != 0 && id != user.ID {
 		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "id in path and body do not match"})
 		return
 	}
+	user.ID = id
+	user.Version = version
 
-	updatedUser, err := c.service.Update(&user)
+	updatedUser, err := c.service.Update(ctx.Request.Context(), &user)
 	if handleError(ctx, err) {
 		return
 	}
 
+	setETag(ctx, updatedUser)
 	ctx.JSON(http.StatusOK, updatedUser)
 }
 
+// RestoreUser godoc
+// @Summary Restore a deleted user
+// @Tags users
+// @Produce json
+// @Param id path string true "User UUID, or integer ID while legacy ID routes are enabled"
+// @Success 200 {object} model.User
+// @Header 200 {string} ETag "User version, send back in If-Match"
+// @Failure 400 {object} model.ErrorResponse "invalid id"
+// @Failure 404 {object} model.ErrorResponse "user not found"
+// @Failure 409 {object} model.ErrorResponse "user is not deleted or its username/email was reused"
+// @Failure 500 {object} model.ErrorResponse "internal server error"
+// @Security ApiKeyAuth || BearerAuth
+// @Router /users/{id}/restore [post]
+func (c *UserController) RestoreUser(ctx *gin.Context) {
+	id, ok := c.userID(ctx)
+	if !ok {
+		return
+	}
+
+	user, err := c.service.Restore(ctx.Request.Context(), id)
+	if handleError(ctx, err) {
+		return
+	}
+
+	setETag(ctx, user)
+	ctx.JSON(http.StatusOK, user)
+}
+
+// PurgeUsers godoc
+// @Summary Purge deleted users
+// @Description Permanently removes users that have been deleted for longer than the configured retention period.
+// @Tags users
+// @Produce json
+// @Success 200 {object} model.PurgeResult
+// @Failure 409 {object} model.ErrorResponse "purging is disabled"
+// @Failure 500 {object} model.ErrorResponse "internal server error"
+// @Security ApiKeyAuth || BearerAuth
+// @Router /users/purge [post]
+func (c *UserController) PurgeUsers(ctx *gin.Context) {
+	purged, err := c.service.Purge(ctx.Request.Context())
+	if handleError(ctx, err) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, model.PurgeResult{Purged: purged})
+}
+
+// PatchUser godoc
+// @Summary Partially update user by UUID
+// @Description Applies an RFC 7396 JSON Merge Patch or an RFC 6902 JSON Patch to the user. Only the patched user is validated, so the patch only needs the changed fields.
+// @Tags users
+// @Accept application/merge-patch+json,application/json-patch+json
+// @Produce json
+// @Param id path string true "User UUID, or integer ID while legacy ID routes are enabled"
+// @Param If-Match header string false "ETag from a previous response, the write fails with 412 when it is stale"
+// @Param patch body object true "Merge patch document or JSON Patch operation list"
+// @Success 200 {object} model.User
+// @Header 200 {string} ETag "User version, send back in If-Match"
+// @Failure 400 {object} model.ErrorResponse "invalid id or patch"
+// @Failure 404 {object} model.ErrorResponse "user not found"
+// @Failure 409 {object} model.ErrorResponse "user with username/email already exists or patch test failed"
+// @Failure 415 {object} model.ErrorResponse "unsupported content type"
+// @Failure 412 {object} model.ErrorResponse "user has been modified"
+// @Failure 413 {object} model.ErrorResponse "patch too large"
+// @Failure 500 {object} model.ErrorResponse "internal server error"
+// @Security ApiKeyAuth || BearerAuth
+// @Router /users/{id} [patch]
+func (c *UserController) PatchUser(ctx *gin.Context) {
+	format, ok := patchFormats[ctx.ContentType()]
+	if !ok {
+		ctx.JSON(http.StatusUnsupportedMediaType, model.ErrorResponse{Error: "content type must be application/merge-patch+json or application/json-patch+json"})
+		return
+	}
+	version, ok := ifMatchVersion(ctx)
+	if !ok {
+		return
+	}
+	id, ok := c.userID(ctx)
+	if !ok {
+		return
+	}
+	patch, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxPatchBytes))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		ctx.JSON(http.StatusRequestEntityTooLarge, model.ErrorResponse{Error: fmt.Sprintf("patch must not be larger than %d bytes", maxPatchBytes)})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid request body"})
+		return
+	}
+
+	patchedUser, err := c.service.Patch(ctx.Request.Context(), id, version, format, patch)
+	if handleError(ctx, err) {
+		return
+	}
+
+	setETag(ctx, patchedUser)
+	ctx.JSON(http.StatusOK, patchedUser)
+}
+
+// maxPatchBytes caps the size of a patch document.
+const maxPatchBytes = 1 << 20
+
+var patchFormats = map[string]service.PatchFormat{
+	"application/merge-patch+json": service.MergePatch,
+	"application/json-patch+json":  service.JSONPatch,
+}
+
+func (c *UserController) userID(ctx *gin.Context) (int64, bool) {
+	return resolveUserID(ctx, c.service, c.legacyIDs)
+}
+
+// resolveUserID resolves the :id path parameter to the user's internal ID. The
+// parameter is the user's UUID or, while legacy ID routes are enabled, the
+// integer ID itself. On failure the error response has already been written.
+func resolveUserID(ctx *gin.Context, users service.UserService, legacyIDs bool) (int64, bool) {
+	param := ctx.Param("id")
+	if uuidRegex.MatchString(param) {
+		// Deleted users are resolved too, so that they can be restored. The
+		// operation itself decides whether it applies to deleted users.
+		user, err := users.GetByUUID(ctx.Request.Context(), param, true)
+		if handleError(ctx, err) {
+			return 0, false
+		}
+		return user.ID, true
+	}
+	if legacyIDs {
+		if id, err := strconv.ParseInt(param, 10, 64); err == nil {
+			return id, true
+		}
+	}
+
+	ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid id"})
+	return 0, false
+}
+
+// includeDeletedParam reads the include_deleted query parameter. On failure
+// the error response has already been written.
+func includeDeletedParam(ctx *gin.Context) (bool, bool) {
+	value := ctx.Query("include_deleted")
+	if value == "" {
+		return false, true
+	}
+	include, err := strconv.ParseBool(value)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "invalid include_deleted"})
+		return false, false
+	}
+	return include, true
+}
+
+var uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// nextPageLink builds an RFC 8288 Link header value pointing at the page
+// after the current one, keeping every other query parameter intact.
+func nextPageLink(current *url.URL, cursor string) string {
+	next := *current
+	query := next.Query()
+	query.Set("cursor", cursor)
+	next.RawQuery = query.Encode()
+	return fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI())
+}
+
 func handleError(ctx *gin.Context, err error) bool {
 	if err == nil {
 		return false
 	}
 
+	ctx.JSON(errorResponse(err))
+	return true
+}
+
+// errorResponse maps a service error to its HTTP status and response body.
+// Unknown errors are not exposed to the client.
+func errorResponse(err error) (int, model.ErrorResponse) {
 	if status, ok := errToStatus[err]; ok {
-		ctx.JSON(status, model.ErrorResponse{Error: err.Error()})
-		return true
+		return status, model.ErrorResponse{Error: err.Error()}
 	}
+	return http.StatusInternalServerError, model.ErrorResponse{Error: "internal server error"}
+}
 
-	ctx.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "internal server error"})
-	return true
+// IsClientError tells whether err is answered with a 4xx status, making it an
+// outcome of the request rather than a failure of the service.
+func IsClientError(err error) bool {
+	status, _ := errorResponse(err)
+	return status < http.StatusInternalServerError
 }
 
 var errToStatus = map[error]int{
@@ -197,4 +549,25 @@ var errToStatus = map[error]int{
 	service.ErrInvalidEmail:          http.StatusBadRequest,
 	service.ErrInvalidUsername:       http.StatusBadRequest,
 	service.ErrInvalidFullName:       http.StatusBadRequest,
+	service.ErrInvalidLimit:          http.StatusBadRequest,
+	service.ErrInvalidCursor:         http.StatusBadRequest,
+	service.ErrInvalidSort:           http.StatusBadRequest,
+	service.ErrInvalidSearchQuery:    http.StatusBadRequest,
+	service.ErrInvalidAuditAction:    http.StatusBadRequest,
+	service.ErrInvalidPatch:          http.StatusBadRequest,
+	service.ErrImmutableIdentifier:   http.StatusBadRequest,
+	service.ErrPatchTestFailed:       http.StatusConflict,
+	service.ErrPreconditionFailed:    http.StatusPreconditionFailed,
+	service.ErrUserNotDeleted:        http.StatusConflict,
+	service.ErrPurgeDisabled:         http.StatusConflict,
+	service.ErrInvalidBulkSize:       http.StatusBadRequest,
+	service.ErrInvalidImportSize:     http.StatusBadRequest,
+	service.ErrBulkAborted:           http.StatusFailedDependency,
+	service.ErrAPIKeyNotFound:        http.StatusNotFound,
+	service.ErrAPIKeyNotActive:       http.StatusConflict,
+	service.ErrInvalidAPIKeyName:     http.StatusBadRequest,
+	service.ErrReservedAPIKeyName:    http.StatusBadRequest,
+	service.ErrInvalidScopes:         http.StatusBadRequest,
+	service.ErrInvalidExpiry:         http.StatusBadRequest,
+	service.ErrInvalidOverlap:        http.StatusBadRequest,
 }
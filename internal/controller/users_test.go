@@ -2,13 +2,19 @@ package controller
 
 import (
 	"bytes"
+	"context"
 	mock_service "cruder/internal/mocks/service"
 	"cruder/internal/model"
 	"cruder/internal/service"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -19,23 +25,31 @@ func setupUserRouter(c *UserController) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
 	r.GET("/users", c.GetAllUsers)
+	r.GET("/users/export", c.ExportUsers)
+	r.GET("/users/search", c.SearchUsers)
 	r.GET("/users/username/:username", c.GetUserByUsername)
+	r.GET("/users/:id", c.GetUser)
 	r.GET("/users/id/:id", c.GetUserByID)
 	r.POST("/users", c.CreateUser)
 	r.DELETE("/users/:id", c.DeleteUser)
 	r.PUT("/users/:id", c.UpdateUser)
+	r.PATCH("/users/:id", c.PatchUser)
+	r.POST("/users/bulk", c.CreateUsersBulk)
+	r.POST("/users/import", c.ImportUsers)
+	r.POST("/users/purge", c.PurgeUsers)
+	r.POST("/users/:id/restore", c.RestoreUser)
 	return r
 }
 
 func TestGetAllUsers_Success(t *testing.T) {
-	// Given: service returns a list of users
+	// Given: service returns a single page of users
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	mockSvc := mock_service.NewMockUserService(ctrl)
-	users := []model.User{{ID: 1, Username: "john"}}
-	mockSvc.EXPECT().GetAll().Return(users, nil)
+	page := &model.UserPage{Users: []model.User{{ID: 1, Username: "john"}}}
+	mockSvc.EXPECT().GetAll(gomock.Any(), model.UserListParams{}).Return(page, nil)
 
-	controller := NewUserController(mockSvc)
+	controller := NewUserController(mockSvc, true)
 	router := setupUserRouter(controller)
 
 	// When: GET /users is called
@@ -43,12 +57,252 @@ func TestGetAllUsers_Success(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	// Then: response should contain the users
+	// Then: response should contain the users and no Link header
 	assert.Equal(t, http.StatusOK, w.Code)
-	var got []model.User
+	var got model.UserPage
 	err := json.Unmarshal(w.Body.Bytes(), &got)
 	assert.NoError(t, err)
-	assert.Equal(t, users, got)
+	assert.Equal(t, *page, got)
+	assert.Empty(t, w.Header().Get("Link"))
+}
+
+func TestGetAllUsers_NextPageLink(t *testing.T) {
+	// Given: service returns a page followed by another page
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+	page := &model.UserPage{Users: []model.User{{ID: 1, Username: "john"}}, NextCursor: "next"}
+	mockSvc.EXPECT().GetAll(gomock.Any(), model.UserListParams{Limit: 1, Cursor: "prev", UsernamePrefix: "jo", Sort: "-username"}).Return(page, nil)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: GET /users is called with limit, cursor, filter and sort
+	req, _ := http.NewRequest("GET", "/users?limit=1&cursor=prev&username_prefix=jo&sort=-username", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should include next_cursor and a Link header to the next page
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got model.UserPage
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	assert.Equal(t, "next", got.NextCursor)
+	assert.Equal(t, `</users?cursor=next&limit=1&sort=-username&username_prefix=jo>; rel="next"`, w.Header().Get("Link"))
+}
+
+func TestGetAllUsers_TimestampFilters(t *testing.T) {
+	// Given: service captures the list parameters
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+	var params model.UserListParams
+	mockSvc.EXPECT().GetAll(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, p model.UserListParams) (*model.UserPage, error) {
+		params = p
+		return &model.UserPage{Users: []model.User{}}, nil
+	})
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: GET /users is called with created_after and updated_after
+	req, _ := http.NewRequest("GET", "/users?created_after=2025-01-01T00:00:00Z&updated_after=2025-06-01T12:00:00.5%2B02:00", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: both RFC 3339 times should be passed to the service
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, params.CreatedAfter.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.True(t, params.UpdatedAfter.Equal(time.Date(2025, 6, 1, 10, 0, 0, 5e8, time.UTC)))
+}
+
+func TestGetAllUsers_InvalidTimestamp(t *testing.T) {
+	// Given: a created_after that is not RFC 3339
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: GET /users?created_after=yesterday is called
+	req, _ := http.NewRequest("GET", "/users?created_after=yesterday", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 400 with error
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var got model.ErrorResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	assert.Equal(t, "invalid query parameters", got.Error)
+}
+
+func exportUsers(users ...model.User) func(context.Context, model.UserListParams, func(*model.User) error) error {
+	return func(_ context.Context, _ model.UserListParams, fn func(*model.User) error) error {
+		for i := range users {
+			if err := fn(&users[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestExportUsers_CSV(t *testing.T) {
+	// Given: service streams two users, one of them deleted
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+	ts := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	users := []model.User{
+		{ID: 1, UUID: "u1", Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe", CreatedAt: ts, UpdatedAt: ts},
+		{ID: 2, UUID: "u2", Username: "jane_doe", Email: "jane@doe.ee", FullName: "Jane Doe", CreatedAt: ts, UpdatedAt: ts, DeletedAt: &ts},
+	}
+	mockSvc.EXPECT().Export(gomock.Any(), model.UserListParams{EmailDomain: "doe.ee", IncludeDeleted: true}, gomock.Any()).
+		DoAndReturn(exportUsers(users...))
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: GET /users/export?format=csv is called with filters
+	req, _ := http.NewRequest("GET", "/users/export?format=csv&email_domain=doe.ee&include_deleted=true", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be a CSV with a header row and a row per user
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "id,uuid,username,email,full_name,created_at,updated_at,deleted_at\n"+
+		"1,u1,john_doe,john@doe.ee,John Doe,2025-01-01T00:00:00Z,2025-01-01T00:00:00Z,\n"+
+		"2,u2,jane_doe,jane@doe.ee,Jane Doe,2025-01-01T00:00:00Z,2025-01-01T00:00:00Z,2025-01-01T00:00:00Z\n", w.Body.String())
+}
+
+func TestExportUsers_NDJSON(t *testing.T) {
+	// Given: service streams two users
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+	mockSvc.EXPECT().Export(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(exportUsers(model.User{ID: 1, Username: "john_doe"}, model.User{ID: 2, Username: "jane_doe"}))
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: GET /users/export?format=ndjson is called
+	req, _ := http.NewRequest("GET", "/users/export?format=ndjson", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should hold one JSON user per line
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	lines := bytes.Split(bytes.TrimSpace(w.Body.Bytes()), []byte("\n"))
+	if assert.Len(t, lines, 2) {
+		var got model.User
+		_ = json.Unmarshal(lines[1], &got)
+		assert.Equal(t, "jane_doe", got.Username)
+	}
+}
+
+func TestExportUsers_InvalidSort(t *testing.T) {
+	// Given: service rejects the sort before streaming anything
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+	mockSvc.EXPECT().Export(gomock.Any(), gomock.Any(), gomock.Any()).Return(service.ErrInvalidSort)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: GET /users/export is called with an unknown sort field
+	req, _ := http.NewRequest("GET", "/users/export?format=csv&sort=password", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be a 400 JSON error rather than a CSV
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var got model.ErrorResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	assert.Equal(t, service.ErrInvalidSort.Error(), got.Error)
+}
+
+func TestExportUsers_InvalidFormat(t *testing.T) {
+	// Given: an unsupported export format
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: GET /users/export?format=xml is called
+	req, _ := http.NewRequest("GET", "/users/export?format=xml", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 400 with error
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestSearchUsers_Success(t *testing.T) {
+	// Given: service finds a single scored match
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+	result := &model.UserSearchResult{Users: []model.ScoredUser{{User: model.User{ID: 1, Username: "john_doe"}, Score: 0.75}}}
+	mockSvc.EXPECT().Search(gomock.Any(), model.UserSearchParams{Query: "jon", Limit: 5}).Return(result, nil)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: GET /users/search?q=jon&limit=5 is called
+	req, _ := http.NewRequest("GET", "/users/search?q=jon&limit=5", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should hold the user with its score next to the user fields
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"username":"john_doe"`)
+	assert.Contains(t, w.Body.String(), `"score":0.75`)
+}
+
+func TestSearchUsers_MissingQuery(t *testing.T) {
+	// Given: service rejects an empty query
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+	mockSvc.EXPECT().Search(gomock.Any(), model.UserSearchParams{}).Return(nil, service.ErrInvalidSearchQuery)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: GET /users/search is called without q
+	req, _ := http.NewRequest("GET", "/users/search", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 400 with error
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetAllUsers_InvalidLimit(t *testing.T) {
+	// Given: a non-numeric limit
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: GET /users?limit=abc is called
+	req, _ := http.NewRequest("GET", "/users?limit=abc", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 400 with error
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var got model.ErrorResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	assert.Equal(t, "invalid query parameters", got.Error)
 }
 
 func TestGetUserByUsername_Success(t *testing.T) {
@@ -57,9 +311,9 @@ func TestGetUserByUsername_Success(t *testing.T) {
 	defer ctrl.Finish()
 	mockSvc := mock_service.NewMockUserService(ctrl)
 	expected := &model.User{ID: 1, Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe"}
-	mockSvc.EXPECT().GetByUsername("john_doe").Return(expected, nil)
+	mockSvc.EXPECT().GetByUsername(gomock.Any(), "john_doe", false).Return(expected, nil)
 
-	controller := NewUserController(mockSvc)
+	controller := NewUserController(mockSvc, true)
 	router := setupUserRouter(controller)
 
 	// When: GET /users/username/john_doe is called
@@ -80,9 +334,9 @@ func TestGetUserByUsername_NotFound(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	mockSvc := mock_service.NewMockUserService(ctrl)
-	mockSvc.EXPECT().GetByUsername("missing").Return(nil, service.ErrUserNotFound)
+	mockSvc.EXPECT().GetByUsername(gomock.Any(), "missing", false).Return(nil, service.ErrUserNotFound)
 
-	controller := NewUserController(mockSvc)
+	controller := NewUserController(mockSvc, true)
 	router := setupUserRouter(controller)
 
 	// When: GET /users/username/missing is called
@@ -102,10 +356,10 @@ func TestGetUserByID_Success(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	mockSvc := mock_service.NewMockUserService(ctrl)
-	expected := &model.User{ID: 1, Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe"}
-	mockSvc.EXPECT().GetByID(int64(1)).Return(expected, nil)
+	expected := &model.User{ID: 1, Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe", Version: 3}
+	mockSvc.EXPECT().GetByID(gomock.Any(), int64(1), false).Return(expected, nil)
 
-	controller := NewUserController(mockSvc)
+	controller := NewUserController(mockSvc, true)
 	router := setupUserRouter(controller)
 
 	// When: GET /users/id/1 is called
@@ -113,12 +367,15 @@ func TestGetUserByID_Success(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	// Then: response should be 200 with the expected user
+	// Then: response should be 200 with the expected user and its version only as ETag
 	assert.Equal(t, http.StatusOK, w.Code)
 	var got model.User
 	err := json.Unmarshal(w.Body.Bytes(), &got)
 	assert.NoError(t, err)
-	assert.Equal(t, *expected, got)
+	want := *expected
+	want.Version = 0
+	assert.Equal(t, want, got)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
 }
 
 func TestGetUserByID_InvalidID(t *testing.T) {
@@ -127,7 +384,7 @@ func TestGetUserByID_InvalidID(t *testing.T) {
 	defer ctrl.Finish()
 	mockSvc := mock_service.NewMockUserService(ctrl)
 
-	controller := NewUserController(mockSvc)
+	controller := NewUserController(mockSvc, true)
 	router := setupUserRouter(controller)
 
 	// When: GET /users/id/abc is called
@@ -142,6 +399,72 @@ func TestGetUserByID_InvalidID(t *testing.T) {
 	assert.Equal(t, "invalid id", got.Error)
 }
 
+func TestGetUser_ByUUID(t *testing.T) {
+	// Given: service returns a user for a UUID
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+	uuid := "123e4567-e89b-12d3-a456-426614174000"
+	expected := &model.User{ID: 1, UUID: uuid, Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe"}
+	mockSvc.EXPECT().GetByUUID(gomock.Any(), uuid, false).Return(expected, nil)
+
+	controller := NewUserController(mockSvc, false)
+	router := setupUserRouter(controller)
+
+	// When: GET /users/{uuid} is called
+	req, _ := http.NewRequest("GET", "/users/"+uuid, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 200 with the expected user
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got model.User
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	assert.Equal(t, *expected, got)
+}
+
+func TestGetUser_IntegerIDWithoutLegacyRoutes(t *testing.T) {
+	// Given: legacy ID routes are disabled
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+
+	controller := NewUserController(mockSvc, false)
+	router := setupUserRouter(controller)
+
+	// When: GET /users/1 is called with an integer ID
+	req, _ := http.NewRequest("GET", "/users/1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 400 with error
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var got model.ErrorResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	assert.Equal(t, "invalid id", got.Error)
+}
+
+func TestDeleteUser_ByUUID(t *testing.T) {
+	// Given: service resolves the UUID to ID 1 and deletes it
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+	uuid := "123e4567-e89b-12d3-a456-426614174000"
+	mockSvc.EXPECT().GetByUUID(gomock.Any(), uuid, true).Return(&model.User{ID: 1, UUID: uuid}, nil)
+	mockSvc.EXPECT().Delete(gomock.Any(), int64(1), int64(0)).Return(nil)
+
+	controller := NewUserController(mockSvc, false)
+	router := setupUserRouter(controller)
+
+	// When: DELETE /users/{uuid} is called
+	req, _ := http.NewRequest("DELETE", "/users/"+uuid, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 204
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
 func TestCreateUser_Success(t *testing.T) {
 	// Given: valid user and service returns created user
 	ctrl := gomock.NewController(t)
@@ -149,9 +472,9 @@ func TestCreateUser_Success(t *testing.T) {
 	mockSvc := mock_service.NewMockUserService(ctrl)
 	input := &model.User{Username: "john", Email: "john@doe.ee", FullName: "John Doe"}
 	created := &model.User{ID: 1, Username: "john", Email: "john@doe.ee", FullName: "John Doe"}
-	mockSvc.EXPECT().Create(input).Return(created, nil)
+	mockSvc.EXPECT().Create(gomock.Any(), input).Return(created, nil)
 
-	controller := NewUserController(mockSvc)
+	controller := NewUserController(mockSvc, true)
 	router := setupUserRouter(controller)
 
 	body, _ := json.Marshal(input)
@@ -175,7 +498,7 @@ func TestCreateUser_InvalidBody(t *testing.T) {
 	defer ctrl.Finish()
 	mockSvc := mock_service.NewMockUserService(ctrl)
 
-	controller := NewUserController(mockSvc)
+	controller := NewUserController(mockSvc, true)
 	router := setupUserRouter(controller)
 
 	// When: POST /users with invalid JSON
@@ -196,9 +519,9 @@ func TestDeleteUser_NotFound(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 	mockSvc := mock_service.NewMockUserService(ctrl)
-	mockSvc.EXPECT().Delete(int64(99)).Return(service.ErrUserNotFound)
+	mockSvc.EXPECT().Delete(gomock.Any(), int64(99), int64(0)).Return(service.ErrUserNotFound)
 
-	controller := NewUserController(mockSvc)
+	controller := NewUserController(mockSvc, true)
 	router := setupUserRouter(controller)
 
 	// When: DELETE /users/99 is called
@@ -219,7 +542,7 @@ func TestUpdateUser_IDMismatch(t *testing.T) {
 	defer ctrl.Finish()
 	mockSvc := mock_service.NewMockUserService(ctrl)
 
-	controller := NewUserController(mockSvc)
+	controller := NewUserController(mockSvc, true)
 	router := setupUserRouter(controller)
 
 	user := model.User{ID: 2, Username: "john", Email: "john@doe.ee", FullName: "John Doe"}
@@ -245,9 +568,9 @@ func TestUpdateUser_Success(t *testing.T) {
 	mockSvc := mock_service.NewMockUserService(ctrl)
 	input := &model.User{ID: 1, Username: "john", Email: "john@doe.ee", FullName: "John Doe"}
 	updated := &model.User{ID: 1, Username: "johnny", Email: "johnny@doe.ee", FullName: "Johnny Doe"}
-	mockSvc.EXPECT().Update(input).Return(updated, nil)
+	mockSvc.EXPECT().Update(gomock.Any(), input).Return(updated, nil)
 
-	controller := NewUserController(mockSvc)
+	controller := NewUserController(mockSvc, true)
 	router := setupUserRouter(controller)
 
 	body, _ := json.Marshal(input)
@@ -265,6 +588,403 @@ func TestUpdateUser_Success(t *testing.T) {
 	assert.Equal(t, *updated, got)
 }
 
+func TestPatchUser_MergePatch(t *testing.T) {
+	// Given: service applies a merge patch to user 1
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+	patch := `{"full_name":"Johnny Doe"}`
+	patched := &model.User{ID: 1, Username: "john", Email: "john@doe.ee", FullName: "Johnny Doe"}
+	mockSvc.EXPECT().Patch(gomock.Any(), int64(1), int64(0), service.MergePatch, []byte(patch)).Return(patched, nil)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: PATCH /users/1 is called with a merge patch
+	req, _ := http.NewRequest("PATCH", "/users/1", bytes.NewBufferString(patch))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 200 with the patched user
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got model.User
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	assert.Equal(t, *patched, got)
+}
+
+func TestPatchUser_UnsupportedContentType(t *testing.T) {
+	// Given: a patch sent as plain JSON
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: PATCH /users/1 is called with application/json
+	req, _ := http.NewRequest("PATCH", "/users/1", bytes.NewBufferString(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 415
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
+
+func TestPatchUser_TooLarge(t *testing.T) {
+	// Given: a patch larger than the limit
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: PATCH /users/1 is called with it
+	patch := `{"full_name":"` + strings.Repeat("x", maxPatchBytes) + `"}`
+	req, _ := http.NewRequest("PATCH", "/users/1", bytes.NewBufferString(patch))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 413 without the service being called
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestUpdateUser_IfMatch(t *testing.T) {
+	// Given: the client sends the version it read as If-Match
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+	input := &model.User{ID: 1, Username: "john", Email: "john@doe.ee", FullName: "John Doe", Version: 2}
+	updated := &model.User{ID: 1, Username: "john", Email: "john@doe.ee", FullName: "John Doe", Version: 3}
+	mockSvc.EXPECT().Update(gomock.Any(), input).Return(updated, nil)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	body, _ := json.Marshal(input)
+
+	// When: PUT /users/1 is called with If-Match "2"
+	req, _ := http.NewRequest("PUT", "/users/1", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"2"`)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 200 with the new version as ETag
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+}
+
+func TestDeleteUser_StaleIfMatch(t *testing.T) {
+	// Given: service reports that the version is stale
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+	mockSvc.EXPECT().Delete(gomock.Any(), int64(1), int64(2)).Return(service.ErrPreconditionFailed)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: DELETE /users/1 is called with If-Match "2"
+	req, _ := http.NewRequest("DELETE", "/users/1", nil)
+	req.Header.Set("If-Match", `"2"`)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 412
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+}
+
+func TestDeleteUser_MalformedIfMatch(t *testing.T) {
+	// Given: an If-Match header that is not an ETag from this API
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: DELETE /users/1 is called with an unquoted If-Match
+	req, _ := http.NewRequest("DELETE", "/users/1", nil)
+	req.Header.Set("If-Match", "abc")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 400
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetUserByUsername_IncludeDeleted(t *testing.T) {
+	// Given: service returns a soft deleted user when deleted users are included
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+	deletedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	expected := &model.User{ID: 1, Username: "john_doe", DeletedAt: &deletedAt}
+	mockSvc.EXPECT().GetByUsername(gomock.Any(), "john_doe", true).Return(expected, nil)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: GET /users/username/john_doe?include_deleted=true is called
+	req, _ := http.NewRequest("GET", "/users/username/john_doe?include_deleted=true", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 200 with the deletion time
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"deleted_at":"2025-01-01T00:00:00Z"`)
+}
+
+func TestCreateUsersBulk_MultiStatus(t *testing.T) {
+	// Given: service creates the first user and rejects the second
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+	users := []model.User{
+		{Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe"},
+		{Username: "jane_doe", Email: "john@doe.ee", FullName: "Jane Doe"},
+	}
+	created := users[0]
+	created.ID = 1
+	mockSvc.EXPECT().CreateBulk(gomock.Any(), users, true).Return([]service.BulkResult{
+		{User: &created},
+		{Err: service.ErrEmailAlreadyExists},
+	}, nil)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: POST /users/bulk?atomic=true is called
+	body, _ := json.Marshal(users)
+	req, _ := http.NewRequest("POST", "/users/bulk?atomic=true", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 207 with a status per user
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	var got model.BulkCreateResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	assert.Equal(t, 1, got.Created)
+	assert.Equal(t, 1, got.Failed)
+	assert.Equal(t, http.StatusCreated, got.Results[0].Status)
+	assert.Equal(t, int64(1), got.Results[0].User.ID)
+	assert.Equal(t, model.BulkCreateResult{Status: http.StatusConflict, Error: "email already exists"}, got.Results[1])
+}
+
+func TestCreateUsersBulk_InvalidBody(t *testing.T) {
+	// Given: a single user object instead of an array
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: POST /users/bulk is called
+	req, _ := http.NewRequest("POST", "/users/bulk", bytes.NewBufferString(`{"username":"john_doe"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 400 with error
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestImportUsers_DryRun(t *testing.T) {
+	// Given: service accepts the first row and rejects the second
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+	users := []model.User{
+		{Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe"},
+		{Username: "jane_doe", Email: "jane@doe.ee", FullName: "Jane, Doe"},
+	}
+	mockSvc.EXPECT().Import(gomock.Any(), users, true).Return([]service.BulkResult{
+		{User: &users[0]},
+		{Err: service.ErrInvalidFullName},
+	}, nil)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: POST /users/import?dry_run=true is called with a spreadsheet export
+	csv := "\ufeffEmail,Username,Full_Name\r\njohn@doe.ee,john_doe,John Doe\r\njane@doe.ee,jane_doe,\"Jane, Doe\"\r\n"
+	req, _ := http.NewRequest("POST", "/users/import?dry_run=true", bytes.NewBufferString(csv))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should report each line
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got model.ImportReport
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	assert.True(t, got.DryRun)
+	assert.Equal(t, 1, got.Valid)
+	assert.Equal(t, 1, got.Failed)
+	assert.Equal(t, model.ImportLine{Line: 2, Username: "john_doe", Status: model.ImportValid}, got.Lines[0])
+	assert.Equal(t, model.ImportLine{Line: 3, Username: "jane_doe", Status: model.ImportFailed, Error: service.ErrInvalidFullName.Error()}, got.Lines[1])
+}
+
+func TestImportUsers_Multipart(t *testing.T) {
+	// Given: service creates the only row
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+	user := model.User{Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe"}
+	created := user
+	created.ID = 1
+	mockSvc.EXPECT().Import(gomock.Any(), []model.User{user}, false).Return([]service.BulkResult{{User: &created}}, nil)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: POST /users/import is called with the CSV as a file upload
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, _ := form.CreateFormFile("file", "users.csv")
+	_, _ = file.Write([]byte("username,email,full_name\njohn_doe,john@doe.ee,John Doe\n"))
+	_ = form.Close()
+	req, _ := http.NewRequest("POST", "/users/import", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should report the created user
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got model.ImportReport
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	assert.Equal(t, model.ImportCreated, got.Lines[0].Status)
+	assert.Equal(t, int64(1), got.Lines[0].User.ID)
+}
+
+func TestImportUsers_MultipartTooLarge(t *testing.T) {
+	// Given: an uploaded file larger than the limit
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: POST /users/import is called with it
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, _ := form.CreateFormFile("file", "users.csv")
+	_, _ = file.Write([]byte("username,email,full_name\n" + strings.Repeat("john_doe,john@doe.ee,John Doe\n", maxImportBytes/30+1)))
+	_ = form.Close()
+	req, _ := http.NewRequest("POST", "/users/import", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 413 without the service being called
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	var got model.ErrorResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	assert.Equal(t, fmt.Sprintf("CSV must not be larger than %d bytes", maxImportBytes), got.Error)
+}
+
+func TestImportUsers_TooLarge(t *testing.T) {
+	// Given: a CSV body larger than the limit
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: POST /users/import is called with it
+	csv := "username,email,full_name\n" + strings.Repeat("john_doe,john@doe.ee,John Doe\n", maxImportBytes/30+1)
+	req, _ := http.NewRequest("POST", "/users/import", bytes.NewBufferString(csv))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 413 without the service being called
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestIsClientError(t *testing.T) {
+	// Given: a service error mapped to 404 and one that is not mapped
+	notFound := service.ErrUserNotFound
+	unknown := errors.New("connection reset")
+
+	// When: they are classified
+	// Then: only the mapped one should be the client's
+	assert.True(t, IsClientError(notFound))
+	assert.False(t, IsClientError(unknown))
+}
+
+func TestImportUsers_MissingColumn(t *testing.T) {
+	// Given: a CSV without the full_name column
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: POST /users/import is called
+	req, _ := http.NewRequest("POST", "/users/import", bytes.NewBufferString("username,email\njohn_doe,john@doe.ee\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 400 naming the missing column
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var got model.ErrorResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	assert.Equal(t, "invalid CSV: header is missing the full_name column", got.Error)
+}
+
+func TestRestoreUser_Success(t *testing.T) {
+	// Given: service restores user 1
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+	restored := &model.User{ID: 1, Username: "john_doe", Version: 4}
+	mockSvc.EXPECT().Restore(gomock.Any(), int64(1)).Return(restored, nil)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: POST /users/1/restore is called
+	req, _ := http.NewRequest("POST", "/users/1/restore", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 200 with the new version as ETag
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+}
+
+func TestPurgeUsers_Success(t *testing.T) {
+	// Given: service purges three users
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockSvc := mock_service.NewMockUserService(ctrl)
+	mockSvc.EXPECT().Purge(gomock.Any()).Return(int64(3), nil)
+
+	controller := NewUserController(mockSvc, true)
+	router := setupUserRouter(controller)
+
+	// When: POST /users/purge is called
+	req, _ := http.NewRequest("POST", "/users/purge", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: response should be 200 with the purged count
+	assert.Equal(t, http.StatusOK, w.Code)
+	var got model.PurgeResult
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	assert.Equal(t, int64(3), got.Purged)
+}
+
 func TestHandleError_UserNotFound(t *testing.T) {
 	// Given: a context and ErrUserNotFound
 	w := httptest.NewRecorder()
@@ -1,7 +1,10 @@
 package handler
 
 import (
+	"cruder/internal/auth"
 	"cruder/internal/controller"
+	"cruder/internal/middleware"
+	"net/http"
 
 	_ "cruder/docs"
 
@@ -10,18 +13,45 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func New(router *gin.Engine, userController *controller.UserController, healthController *controller.HealthController) *gin.Engine {
+func New(router *gin.Engine, userController *controller.UserController, auditController *controller.AuditController, apiKeyController *controller.APIKeyController, healthController *controller.HealthController, limiter *middleware.RateLimiter, metrics http.Handler, legacyIDRoutes bool) *gin.Engine {
+	read := middleware.RequireScope(auth.ScopeUsersRead)
+	write := middleware.RequireScope(auth.ScopeUsersWrite)
+	audit := middleware.RequireScope(auth.ScopeAuditRead)
+	admin := middleware.RequireScope(auth.ScopeKeysAdmin)
+
 	router.GET("/healthz", healthController.HealthCheck)
+	router.GET("/readyz", healthController.Readiness)
+	router.GET("/version", healthController.Version)
+	router.GET("/metrics", gin.WrapH(metrics))
 	v1 := router.Group("/api/v1")
 	{
-		userGroup := v1.Group("/users")
+		userGroup := v1.Group("/users", limiter.Group("users"))
+		{
+			userGroup.GET("/", read, userController.GetAllUsers)
+			userGroup.GET("/export", read, userController.ExportUsers)
+			userGroup.GET("/search", read, userController.SearchUsers)
+			userGroup.GET("/username/:username", read, userController.GetUserByUsername)
+			userGroup.GET("/:id", read, userController.GetUser)
+			userGroup.GET("/:id/audit", audit, auditController.GetUserAudit)
+			if legacyIDRoutes {
+				userGroup.GET("/id/:id", read, userController.GetUserByID)
+			}
+			userGroup.POST("/", write, userController.CreateUser)
+			userGroup.POST("/bulk", write, userController.CreateUsersBulk)
+			userGroup.POST("/import", write, userController.ImportUsers)
+			userGroup.POST("/purge", write, userController.PurgeUsers)
+			userGroup.POST("/:id/restore", write, userController.RestoreUser)
+			userGroup.DELETE("/:id", write, userController.DeleteUser)
+			userGroup.PUT("/:id", write, userController.UpdateUser)
+			userGroup.PATCH("/:id", write, userController.PatchUser)
+		}
+		v1.GET("/audit", limiter.Group("audit"), audit, auditController.ListAudit)
+		keyGroup := v1.Group("/api-keys", limiter.Group("api_keys"), admin)
 		{
-			userGroup.GET("/", userController.GetAllUsers)
-			userGroup.GET("/username/:username", userController.GetUserByUsername)
-			userGroup.GET("/id/:id", userController.GetUserByID)
-			userGroup.POST("/", userController.CreateUser)
-			userGroup.DELETE("/:id", userController.DeleteUser)
-			userGroup.PUT("/:id", userController.UpdateUser)
+			keyGroup.GET("", apiKeyController.ListAPIKeys)
+			keyGroup.POST("", apiKeyController.CreateAPIKey)
+			keyGroup.DELETE("/:id", apiKeyController.RevokeAPIKey)
+			keyGroup.POST("/:id/rotate", apiKeyController.RotateAPIKey)
 		}
 	}
 
@@ -0,0 +1,17 @@
+package logging
+
+import (
+	"io"
+	"log/slog"
+)
+
+// New creates a logger writing records from level on to w, as JSON or, with
+// the text format, as key=value pairs. Request IDs are added from the context.
+func New(w io.Writer, level slog.Level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler = slog.NewJSONHandler(w, opts)
+	if format == "text" {
+		h = slog.NewTextHandler(w, opts)
+	}
+	return slog.New(NewContextHandler(h))
+}
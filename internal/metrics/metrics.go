@@ -0,0 +1,46 @@
+// Package metrics defines the Prometheus metrics the service exposes on
+// /metrics.
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const namespace = "cruder"
+
+type Metrics struct {
+	// HTTPRequests counts requests by route template, method and status.
+	HTTPRequests *prometheus.CounterVec
+	// HTTPDuration observes request latency by route template, method and
+	// status.
+	HTTPDuration *prometheus.HistogramVec
+	// QueryDuration observes the duration of repository methods by
+	// repository, method and whether they failed.
+	QueryDuration *prometheus.HistogramVec
+}
+
+// New creates the metrics and registers them on reg.
+func New(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		HTTPRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests by route, method and status.",
+		}, []string{"route", "method", "status"}),
+		HTTPDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by route, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		QueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "db",
+			Name:      "query_duration_seconds",
+			Help:      "Repository method duration by repository, method and outcome.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"repository", "method", "outcome"}),
+	}
+	reg.MustRegister(m.HTTPRequests, m.HTTPDuration, m.QueryDuration)
+	return m
+}
@@ -1,23 +1,32 @@
 package middleware
 
 import (
+	"cruder/internal/auth"
+	"errors"
 	"slices"
 
 	"github.com/gin-gonic/gin"
 )
 
+// APIKeyNameKey and APIKeyIDKey are the gin context keys holding the name and
+// the ID of the API key a request was authenticated with.
+const (
+	APIKeyNameKey = "api_key_name"
+	APIKeyIDKey   = "api_key_id"
+)
+
 type ApiKeyMiddleware struct {
-	apiKey  string
+	keys    auth.KeyStore
 	ignored []string
 }
 
-func NewApiKeyMiddleware(apiKey string, ignored []string) *ApiKeyMiddleware {
-	return &ApiKeyMiddleware{apiKey: apiKey, ignored: ignored}
+func NewApiKeyMiddleware(keys auth.KeyStore, ignored []string) *ApiKeyMiddleware {
+	return &ApiKeyMiddleware{keys: keys, ignored: ignored}
 }
 
 func (am *ApiKeyMiddleware) Handler() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if slices.Contains(am.ignored, c.FullPath()) {
+		if slices.Contains(am.ignored, c.FullPath()) || authenticated(c) {
 			c.Next()
 			return
 		}
@@ -27,10 +36,20 @@ func (am *ApiKeyMiddleware) Handler() gin.HandlerFunc {
 			c.AbortWithStatusJSON(401, gin.H{"error": "X-Api-Key header is missing"})
 			return
 		}
-		if providedKey != am.apiKey {
+		key, err := am.keys.Lookup(c.Request.Context(), providedKey)
+		if errors.Is(err, auth.ErrUnknownKey) {
 			c.AbortWithStatusJSON(403, gin.H{"error": "provided X-Api-Key is invalid"})
 			return
 		}
+		if err != nil {
+			_ = c.Error(err)
+			c.AbortWithStatusJSON(500, gin.H{"error": "internal server error"})
+			return
+		}
+
+		c.Set(APIKeyNameKey, key.Name)
+		c.Set(APIKeyIDKey, key.ID)
+		authenticate(c, "key:"+key.ID, key.Scopes)
 		c.Next()
 	}
 }
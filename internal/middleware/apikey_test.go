@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"cruder/internal/auth"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -9,11 +10,18 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+// helper to create a key store holding a single read only key named crm
+func newKeyStore(secret string) auth.KeyStore {
+	return auth.NewStaticKeyStore(map[string]auth.Key{
+		secret: {ID: "cruder_0123456789ab", Name: "crm", Scopes: []auth.Scope{auth.ScopeUsersRead}},
+	})
+}
+
 // helper to create a test router
 func setupRouter(apiKey string, ignored []string) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
-	r.Use(NewApiKeyMiddleware(apiKey, ignored).Handler())
+	r.Use(NewApiKeyMiddleware(newKeyStore(apiKey), ignored).Handler())
 	r.GET("/ping", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "pong"})
 	})
@@ -78,3 +86,51 @@ func TestApiKeyMiddleware_IgnoredEndpoint(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "pong")
 }
+
+// Given: A request with a valid API key header
+func TestApiKeyMiddleware_SetsActor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(NewApiKeyMiddleware(newKeyStore("secret"), nil).Handler())
+	var actor, name string
+	r.GET("/ping", func(c *gin.Context) {
+		actor = auth.Actor(c.Request.Context())
+		name = c.GetString(APIKeyNameKey)
+	})
+	req, _ := http.NewRequest("GET", "/ping", nil)
+	req.Header.Set("X-Api-Key", "secret")
+
+	// When: The request is sent
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// Then: Changes made by the handler should be attributed to the key ID
+	assert.Equal(t, "key:cruder_0123456789ab", actor)
+	assert.Equal(t, "crm", name)
+}
+
+// Given: A key that was granted users:read but not users:write
+func TestRequireScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(NewApiKeyMiddleware(newKeyStore("secret"), nil).Handler())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/users", RequireScope(auth.ScopeUsersRead), ok)
+	r.POST("/users", RequireScope(auth.ScopeUsersWrite), ok)
+
+	// When: The key is used to read and to write
+	read := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/users", nil)
+	req.Header.Set("X-Api-Key", "secret")
+	r.ServeHTTP(read, req)
+
+	write := httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/users", nil)
+	req.Header.Set("X-Api-Key", "secret")
+	r.ServeHTTP(write, req)
+
+	// Then: Reading should be allowed and writing forbidden
+	assert.Equal(t, http.StatusOK, read.Code)
+	assert.Equal(t, http.StatusForbidden, write.Code)
+	assert.Contains(t, write.Body.String(), "missing the users:write scope")
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"cruder/internal/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScopesKey is the gin context key holding the scopes granted to the caller.
+// It is set by whichever authentication middleware accepted the request.
+const ScopesKey = "scopes"
+
+// authenticate records who the request was authenticated as, both on the gin
+// context and on the request context that is passed down to the services.
+func authenticate(c *gin.Context, actor string, scopes []auth.Scope) {
+	c.Set(ScopesKey, scopes)
+	ctx := auth.WithActor(c.Request.Context(), actor)
+	c.Request = c.Request.WithContext(auth.WithScopes(ctx, scopes))
+}
+
+// authenticated reports whether an earlier middleware already accepted the
+// request.
+func authenticated(c *gin.Context) bool {
+	_, ok := c.Get(ScopesKey)
+	return ok
+}
+
+// RequireScope rejects requests whose caller was not granted scope.
+func RequireScope(scope auth.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !auth.HasScope(c.Request.Context(), scope) {
+			c.AbortWithStatusJSON(403, gin.H{"error": "missing the " + string(scope) + " scope"})
+			return
+		}
+		c.Next()
+	}
+}
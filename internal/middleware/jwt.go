@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"cruder/internal/auth"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SubjectKey is the gin context key holding the subject of the bearer token a
+// request was authenticated with.
+const SubjectKey = "jwt_subject"
+
+// DefaultScopeClaim is the claim scopes are read from unless configured
+// otherwise. It holds either a space separated string or a list of strings.
+const DefaultScopeClaim = "scope"
+
+// jwtLeeway allows for clock skew between the token issuer and this service.
+const jwtLeeway = 30 * time.Second
+
+type JWTOptions struct {
+	Issuer     string
+	Audience   string
+	ScopeClaim string
+}
+
+// JWTAuth authenticates requests carrying an Authorization: Bearer token.
+// Requests without one are passed on untouched, so that it can run in front of
+// ApiKeyMiddleware, which then skips the requests JWTAuth accepted.
+type JWTAuth struct {
+	keys       jwt.Keyfunc
+	parser     *jwt.Parser
+	scopeClaim string
+	ignored    []string
+}
+
+// NewJWTAuth creates the middleware. Tokens must be signed with HS256, RS256
+// or ES256 by a key keys returns, and carry the configured issuer and
+// audience, an expiry and a subject.
+func NewJWTAuth(keys jwt.Keyfunc, opts JWTOptions, ignored []string) *JWTAuth {
+	scopeClaim := opts.ScopeClaim
+	if scopeClaim == "" {
+		scopeClaim = DefaultScopeClaim
+	}
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{"HS256", "RS256", "ES256"}),
+		jwt.WithIssuer(opts.Issuer),
+		jwt.WithAudience(opts.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(jwtLeeway),
+	)
+	return &JWTAuth{keys: keys, parser: parser, scopeClaim: scopeClaim, ignored: ignored}
+}
+
+func (jm *JWTAuth) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, token, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !strings.EqualFold(scheme, "Bearer") || slices.Contains(jm.ignored, c.FullPath()) {
+			c.Next()
+			return
+		}
+
+		claims := jwt.MapClaims{}
+		if _, err := jm.parser.ParseWithClaims(strings.TrimSpace(token), claims, jm.keys); err != nil {
+			jm.reject(c)
+			return
+		}
+		subject, _ := claims.GetSubject()
+		if subject == "" {
+			jm.reject(c)
+			return
+		}
+
+		c.Set(SubjectKey, subject)
+		authenticate(c, "sub:"+subject, scopesFromClaim(claims[jm.scopeClaim]))
+		c.Next()
+	}
+}
+
+func (jm *JWTAuth) reject(c *gin.Context) {
+	c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+	c.AbortWithStatusJSON(401, gin.H{"error": "bearer token is invalid"})
+}
+
+// scopesFromClaim reads the scopes out of a claim holding either a space
+// separated string or a list of strings. Scopes this service does not know,
+// such as openid, are left out.
+func scopesFromClaim(claim any) []auth.Scope {
+	var names []string
+	switch v := claim.(type) {
+	case string:
+		names = strings.Fields(v)
+	case []any:
+		for _, name := range v {
+			if s, ok := name.(string); ok {
+				names = append(names, s)
+			}
+		}
+	}
+
+	scopes := []auth.Scope{}
+	for _, name := range names {
+		if scope, err := auth.ParseScope(name); err == nil {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
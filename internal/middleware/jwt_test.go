@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"cruder/internal/auth"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+var jwtSecret = []byte("secret-secret-secret-secret-secret")
+
+// helper to create a router running JWTAuth in front of ApiKeyMiddleware
+func setupJWTRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	keys := func(*jwt.Token) (any, error) { return jwtSecret, nil }
+	r.Use(NewJWTAuth(keys, JWTOptions{Issuer: "https://id.example.com", Audience: "cruder"}, nil).Handler())
+	r.Use(NewApiKeyMiddleware(newKeyStore("secret"), nil).Handler())
+	r.GET("/users", RequireScope(auth.ScopeUsersRead), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"actor": auth.Actor(c.Request.Context()), "subject": c.GetString(SubjectKey)})
+	})
+	return r
+}
+
+func signToken(claims jwt.MapClaims) string {
+	base := jwt.MapClaims{
+		"iss":   "https://id.example.com",
+		"aud":   "cruder",
+		"sub":   "alice",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": "openid users:read",
+	}
+	for k, v := range claims {
+		base[k] = v
+	}
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, base).SignedString(jwtSecret)
+	return token
+}
+
+// Given: A valid bearer token granting users:read
+func TestJWTAuth_ValidToken(t *testing.T) {
+	r := setupJWTRouter()
+	req, _ := http.NewRequest("GET", "/users", nil)
+	req.Header.Set("Authorization", "Bearer "+signToken(nil))
+
+	// When: The request is sent without an API key
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// Then: It should be allowed and attributed to the token subject
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"actor":"sub:alice","subject":"alice"}`, w.Body.String())
+}
+
+// Given: Bearer tokens failing one of the checks
+func TestJWTAuth_InvalidToken(t *testing.T) {
+	tests := map[string]jwt.MapClaims{
+		"wrong issuer":   {"iss": "https://other.example.com"},
+		"wrong audience": {"aud": "billing"},
+		"expired":        {"exp": time.Now().Add(-time.Hour).Unix()},
+		"no expiry":      {"exp": nil},
+		"no subject":     {"sub": ""},
+	}
+	for name, claims := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := setupJWTRouter()
+			req, _ := http.NewRequest("GET", "/users", nil)
+			req.Header.Set("Authorization", "Bearer "+signToken(claims))
+			req.Header.Set("X-Api-Key", "secret")
+
+			// When: The request is sent, even with a valid API key
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			// Then: It should return 401 Unauthorized
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.Contains(t, w.Header().Get("WWW-Authenticate"), "invalid_token")
+		})
+	}
+}
+
+// Given: A token whose scope claim is a list without users:read
+func TestJWTAuth_ScopeList(t *testing.T) {
+	r := setupJWTRouter()
+	req, _ := http.NewRequest("GET", "/users", nil)
+	req.Header.Set("Authorization", "Bearer "+signToken(jwt.MapClaims{"scope": []string{"users:write"}}))
+
+	// When: The request is sent
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// Then: It should return 403 Forbidden
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+// Given: A request with an API key and no bearer token
+func TestJWTAuth_FallsBackToApiKey(t *testing.T) {
+	r := setupJWTRouter()
+	req, _ := http.NewRequest("GET", "/users", nil)
+	req.Header.Set("X-Api-Key", "secret")
+
+	// When: The request is sent
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// Then: It should be allowed and attributed to the API key
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"actor":"key:cruder_0123456789ab","subject":""}`, w.Body.String())
+}
@@ -31,6 +31,13 @@ func (lm *LoggerMiddleware) Handler() gin.HandlerFunc {
 			slog.String("client_ip", c.ClientIP()),
 			slog.String("user_agent", c.Request.UserAgent()),
 			slog.Int("status", status),
+			slog.String("request_id", c.GetString(RequestIDKey)),
+		}
+		if name := c.GetString(APIKeyNameKey); name != "" {
+			attrs = append(attrs, slog.String("api_key", name), slog.String("api_key_id", c.GetString(APIKeyIDKey)))
+		}
+		if subject := c.GetString(SubjectKey); subject != "" {
+			attrs = append(attrs, slog.String("subject", subject))
 		}
 
 		args := make([]any, len(attrs))
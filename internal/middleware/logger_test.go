@@ -17,6 +17,7 @@ func setupLoggerRouter(buf *bytes.Buffer) *gin.Engine {
 	handler := slog.NewTextHandler(buf, nil)
 	logger := slog.New(handler)
 	r := gin.New()
+	r.Use(NewRequestIDMiddleware().Handler())
 	r.Use(NewLoggerMiddleWare(logger).Handler())
 
 	// endpoints return different status codes
@@ -26,6 +27,11 @@ func setupLoggerRouter(buf *bytes.Buffer) *gin.Engine {
 	r.GET("/bad", func(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
 	})
+	r.GET("/authenticated", func(c *gin.Context) {
+		c.Set(APIKeyNameKey, "crm")
+		c.Set(APIKeyIDKey, "cruder_0123456789ab")
+		c.Status(http.StatusNoContent)
+	})
 	r.GET("/fail", func(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server error"})
 	})
@@ -40,6 +46,7 @@ func TestLoggerMiddleware_LogsExpectedFields(t *testing.T) {
 	// Given: A GET /ok request with query string and User-Agent
 	req, _ := http.NewRequest("GET", "/ok?foo=bar", nil)
 	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("X-Request-ID", "req-1")
 
 	// When: The request is served
 	w := httptest.NewRecorder()
@@ -54,6 +61,7 @@ func TestLoggerMiddleware_LogsExpectedFields(t *testing.T) {
 	assert.Contains(t, logOutput, "user_agent=test-agent")
 	assert.Contains(t, logOutput, "status=200")
 	assert.Contains(t, logOutput, "latency_ms=")
+	assert.Contains(t, logOutput, "request_id=req-1")
 }
 
 func TestLoggerMiddleware_InfoLog(t *testing.T) {
@@ -109,3 +117,19 @@ func TestLoggerMiddleware_ErrorLog(t *testing.T) {
 	assert.Contains(t, logOutput, "level=ERROR")
 	assert.Contains(t, logOutput, "path=/fail")
 }
+
+func TestLoggerMiddleware_LogsAPIKeyName(t *testing.T) {
+	var buf bytes.Buffer
+	r := setupLoggerRouter(&buf)
+
+	// Given: A request authenticated with the API key named crm
+	req, _ := http.NewRequest("GET", "/authenticated", nil)
+
+	// When: The request is served through the middleware
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// Then: The log should name the API key
+	assert.Contains(t, buf.String(), "api_key=crm")
+	assert.Contains(t, buf.String(), "api_key_id=cruder_0123456789ab")
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"cruder/internal/metrics"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels requests that matched no route, so that probing for
+// random paths cannot blow up the number of series.
+const unmatchedRoute = "unmatched"
+
+type MetricsMiddleware struct {
+	metrics *metrics.Metrics
+}
+
+func NewMetricsMiddleware(m *metrics.Metrics) *MetricsMiddleware {
+	return &MetricsMiddleware{metrics: m}
+}
+
+// Handler counts and times requests by route template rather than path, so
+// that /users/:id is a single series however many users there are. It must be
+// registered before gin.Recovery, or requests that panic are not counted.
+func (mm *MetricsMiddleware) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		mm.metrics.HTTPRequests.WithLabelValues(route, c.Request.Method, status).Inc()
+		mm.metrics.HTTPDuration.WithLabelValues(route, c.Request.Method, status).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"cruder/internal/metrics"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+// Given: Requests to a parameterised route and to an unknown path
+func TestMetricsMiddleware_LabelsByRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	reg := prometheus.NewRegistry()
+	r := gin.New()
+	r.Use(NewMetricsMiddleware(metrics.New(reg)).Handler())
+	r.GET("/users/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// When: Two users and a missing path are requested
+	for _, path := range []string{"/users/1", "/users/2", "/nope"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// Then: The users should share the route template series and the path should be unmatched
+	assert.Equal(t, map[string]float64{"/users/:id GET 200": 2, "unmatched GET 404": 1}, requestCounts(t, reg))
+}
+
+// Given: A route that panics, recovered inside the metrics middleware
+func TestMetricsMiddleware_CountsPanics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	reg := prometheus.NewRegistry()
+	r := gin.New()
+	r.Use(NewMetricsMiddleware(metrics.New(reg)).Handler())
+	r.Use(gin.Recovery())
+	r.GET("/users/:id", func(c *gin.Context) { panic("boom") })
+
+	// When: The route is requested
+	req, _ := http.NewRequest("GET", "/users/1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// Then: The request should be counted as the 500 it was answered with
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, map[string]float64{"/users/:id GET 500": 1}, requestCounts(t, reg))
+}
+
+// helper to read the request counter by "route method status"
+func requestCounts(t *testing.T, reg *prometheus.Registry) map[string]float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	assert.NoError(t, err)
+	counts := map[string]float64{}
+	for _, f := range families {
+		if f.GetName() != "cruder_http_requests_total" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			counts[labels["route"]+" "+labels["method"]+" "+labels["status"]] = m.GetCounter().GetValue()
+		}
+	}
+	return counts
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// DefaultRateLimitGroup is the group whose limit applies to route groups
+// without one of their own.
+const DefaultRateLimitGroup = "default"
+
+// ClientIPRateLimitGroup is the group limiting every request by client IP
+// before it is authenticated, so that requests with bad credentials are
+// limited too.
+const ClientIPRateLimitGroup = "client_ip"
+
+// bucketIdleTimeout is how long a caller's bucket is kept after its last
+// request. A bucket left alone for this long would be full again anyway.
+const bucketIdleTimeout = 10 * time.Minute
+
+// RateLimit allows Burst requests at once, refilled at Rate requests per
+// second. A zero Rate disables limiting.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimiter keeps a token bucket per route group and caller. Callers are
+// told apart by the API key or token subject they authenticated with, or by
+// their IP address otherwise, so Group must run after the authentication
+// middlewares. ClientIP runs before them.
+type RateLimiter struct {
+	limits map[string]RateLimit
+	now    func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter creates a rate limiter with limits per route group name.
+func NewRateLimiter(limits map[string]RateLimit) *RateLimiter {
+	return &RateLimiter{limits: limits, now: time.Now, buckets: make(map[string]*bucket)}
+}
+
+// Group returns the middleware limiting the named route group.
+func (rl *RateLimiter) Group(name string) gin.HandlerFunc {
+	return rl.limit(name, callerIdentity, nil)
+}
+
+// ClientIP returns the middleware limiting every request but those to the
+// ignored routes by client IP, under the client_ip group. The IP is the one gin
+// resolves, so the engine's trusted proxies must include the ones in front.
+func (rl *RateLimiter) ClientIP(ignored []string) gin.HandlerFunc {
+	return rl.limit(ClientIPRateLimitGroup, func(c *gin.Context) string { return "ip:" + c.ClientIP() }, ignored)
+}
+
+func (rl *RateLimiter) limit(name string, identity func(*gin.Context) string, ignored []string) gin.HandlerFunc {
+	limit, ok := rl.limits[name]
+	if !ok {
+		limit = rl.limits[DefaultRateLimitGroup]
+	}
+	if limit.Rate <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+
+	return func(c *gin.Context) {
+		if slices.Contains(ignored, c.FullPath()) {
+			c.Next()
+			return
+		}
+		now := rl.now()
+		limiter := rl.bucket(name+"|"+identity(c), limit, now)
+
+		reservation := limiter.ReserveN(now, 1)
+		delay := reservation.DelayFrom(now)
+		if delay > 0 {
+			// Give the token back, a rejected request should not push the
+			// next allowed one further out.
+			reservation.CancelAt(now)
+		}
+
+		tokens := limiter.TokensAt(now)
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		c.Header("RateLimit-Remaining", strconv.Itoa(max(0, int(math.Floor(tokens)))))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(time.Duration((float64(limit.Burst)-tokens)/limit.Rate*float64(time.Second)))))
+
+		if delay > 0 {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(delay)))
+			c.AbortWithStatusJSON(429, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// bucket returns the bucket of key, creating it if needed, and drops the
+// buckets that have been idle for a while.
+func (rl *RateLimiter) bucket(key string, limit RateLimit, now time.Time) *rate.Limiter {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if now.Sub(rl.lastSweep) > bucketIdleTimeout {
+		for k, b := range rl.buckets {
+			if now.Sub(b.lastSeen) > bucketIdleTimeout {
+				delete(rl.buckets, k)
+			}
+		}
+		rl.lastSweep = now
+	}
+
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst)}
+		rl.buckets[key] = b
+	}
+	b.lastSeen = now
+	return b.limiter
+}
+
+// callerIdentity names the caller a request counts against.
+func callerIdentity(c *gin.Context) string {
+	if id := c.GetString(APIKeyIDKey); id != "" {
+		return "key:" + id
+	}
+	if subject := c.GetString(SubjectKey); subject != "" {
+		return "sub:" + subject
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// helper to create a router limiting the users group, with a clock the test controls
+func setupRateLimitRouter(limits map[string]RateLimit, now *time.Time) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	limiter := NewRateLimiter(limits)
+	limiter.now = func() time.Time { return *now }
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if key := c.GetHeader("X-Api-Key"); key != "" {
+			c.Set(APIKeyIDKey, key)
+		}
+	})
+	r.GET("/users", limiter.Group("users"), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/audit", limiter.Group("audit"), func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func sendAs(r *gin.Engine, path, key string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Header.Set("X-Api-Key", key)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// Given: A users limit of 1 request per second with a burst of 2
+func TestRateLimiter_ExhaustsBurst(t *testing.T) {
+	now := time.Now()
+	r := setupRateLimitRouter(map[string]RateLimit{"users": {Rate: 1, Burst: 2}}, &now)
+
+	// When: One key sends three requests at once, then another key sends one
+	first := sendAs(r, "/users", "crm")
+	second := sendAs(r, "/users", "crm")
+	third := sendAs(r, "/users", "crm")
+	other := sendAs(r, "/users", "erp")
+
+	// Then: The third request should be rejected with headers telling when to retry
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "2", first.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", first.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, "0", second.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2", second.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, http.StatusTooManyRequests, third.Code)
+	assert.Equal(t, "1", third.Header().Get("Retry-After"))
+	assert.Equal(t, "0", third.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, http.StatusOK, other.Code, "expected other keys to have their own bucket")
+}
+
+// Given: An exhausted bucket
+func TestRateLimiter_Refills(t *testing.T) {
+	now := time.Now()
+	r := setupRateLimitRouter(map[string]RateLimit{"users": {Rate: 1, Burst: 1}}, &now)
+	sendAs(r, "/users", "crm")
+	rejected := sendAs(r, "/users", "crm")
+
+	// When: A second passes
+	now = now.Add(time.Second)
+	allowed := sendAs(r, "/users", "crm")
+
+	// Then: The next request should be allowed again
+	assert.Equal(t, http.StatusTooManyRequests, rejected.Code)
+	assert.Equal(t, http.StatusOK, allowed.Code)
+}
+
+// Given: A default limit and no limit of the audit group's own
+func TestRateLimiter_DefaultGroup(t *testing.T) {
+	now := time.Now()
+	r := setupRateLimitRouter(map[string]RateLimit{DefaultRateLimitGroup: {Rate: 1, Burst: 1}}, &now)
+
+	// When: The audit and users groups are called twice each
+	audit := []int{sendAs(r, "/audit", "crm").Code, sendAs(r, "/audit", "crm").Code}
+	users := []int{sendAs(r, "/users", "crm").Code, sendAs(r, "/users", "crm").Code}
+
+	// Then: Each group should get its own bucket with the default limit
+	assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests}, audit)
+	assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests}, users)
+}
+
+// Given: No limits configured
+func TestRateLimiter_Disabled(t *testing.T) {
+	now := time.Now()
+	r := setupRateLimitRouter(nil, &now)
+
+	// When: An anonymous caller sends many requests
+	var codes []int
+	for range 5 {
+		codes = append(codes, sendAs(r, "/users", "").Code)
+	}
+
+	// Then: None should be limited or carry rate limit headers
+	assert.NotContains(t, codes, http.StatusTooManyRequests)
+	assert.Empty(t, sendAs(r, "/users", "").Header().Get("RateLimit-Limit"))
+}
+
+// Given: A client IP limit of one request that runs before authentication
+func TestRateLimiter_ClientIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	now := time.Now()
+	limiter := NewRateLimiter(map[string]RateLimit{ClientIPRateLimitGroup: {Rate: 1, Burst: 1}})
+	limiter.now = func() time.Time { return now }
+	r := gin.New()
+	r.Use(limiter.ClientIP([]string{"/healthz"}))
+	r.Use(NewApiKeyMiddleware(newKeyStore("secret"), []string{"/healthz"}).Handler())
+	r.GET("/users", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/healthz", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	// When: A client sends two requests with a wrong key and probes the health check
+	first := sendAs(r, "/users", "wrong")
+	second := sendAs(r, "/users", "wrong")
+	health := sendAs(r, "/healthz", "")
+
+	// Then: The second request should be limited before its key is looked up
+	assert.Equal(t, http.StatusForbidden, first.Code)
+	assert.Equal(t, http.StatusTooManyRequests, second.Code)
+	assert.Equal(t, http.StatusOK, health.Code, "expected ignored routes not to be limited")
+}
+
+// Given: A client IP limit of one request and clients behind a trusted proxy
+func TestRateLimiter_ClientIPBehindTrustedProxy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter := NewRateLimiter(map[string]RateLimit{ClientIPRateLimitGroup: {Rate: 1, Burst: 1}})
+	r := gin.New()
+	assert.NoError(t, r.SetTrustedProxies([]string{"10.0.0.0/8"}))
+	r.Use(limiter.ClientIP(nil))
+	r.GET("/users", func(c *gin.Context) { c.Status(http.StatusOK) })
+	sendFrom := func(client string) int {
+		req, _ := http.NewRequest("GET", "/users", nil)
+		req.RemoteAddr = "10.0.0.5:40000"
+		req.Header.Set("X-Forwarded-For", client)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// When: Two clients send a request through the proxy, then the first sends another
+	first := sendFrom("1.1.1.1")
+	second := sendFrom("2.2.2.2")
+	again := sendFrom("1.1.1.1")
+
+	// Then: Each client should have its own bucket
+	assert.Equal(t, http.StatusOK, first)
+	assert.Equal(t, http.StatusOK, second)
+	assert.Equal(t, http.StatusTooManyRequests, again)
+}
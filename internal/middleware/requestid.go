@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"cruder/internal/logging"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDKey is the gin context key holding the request ID.
+	RequestIDKey = "request_id"
+	// maxRequestIDLength caps the length of request IDs taken from clients.
+	maxRequestIDLength = 128
+)
+
+type RequestIDMiddleware struct{}
+
+func NewRequestIDMiddleware() *RequestIDMiddleware {
+	return &RequestIDMiddleware{}
+}
+
+// Handler takes the request ID from the X-Request-ID header, or generates one
+// when the header is missing or unfit for logs, and echoes it back in the
+// response.
+func (rm *RequestIDMiddleware) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		c.Set(RequestIDKey, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// validRequestID accepts IDs of printable ASCII without spaces, so that they
+// can be logged and echoed as they are.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
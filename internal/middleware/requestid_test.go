@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"cruder/internal/logging"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// helper to create a router that reports the request ID its handler sees
+func setupRequestIDRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(NewRequestIDMiddleware().Handler())
+	r.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, logging.RequestID(c.Request.Context()))
+	})
+	return r
+}
+
+// Given: A request carrying an X-Request-ID header
+func TestRequestIDMiddleware_AcceptsHeader(t *testing.T) {
+	r := setupRequestIDRouter()
+	req, _ := http.NewRequest("GET", "/ping", nil)
+	req.Header.Set("X-Request-ID", "client-42")
+
+	// When: The request is sent
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// Then: The ID should reach the handler and be echoed back
+	assert.Equal(t, "client-42", w.Body.String())
+	assert.Equal(t, "client-42", w.Header().Get("X-Request-ID"))
+}
+
+// Given: Requests with a missing or unusable X-Request-ID header
+func TestRequestIDMiddleware_GeneratesID(t *testing.T) {
+	for name, header := range map[string]string{
+		"missing":  "",
+		"spaces":   "id with spaces",
+		"too long": strings.Repeat("a", 129),
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := setupRequestIDRouter()
+			req, _ := http.NewRequest("GET", "/ping", nil)
+			req.Header.Set("X-Request-ID", header)
+
+			// When: The request is sent
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			// Then: A new ID should be generated, passed on and echoed back
+			assert.Len(t, w.Body.String(), 32)
+			assert.Equal(t, w.Body.String(), w.Header().Get("X-Request-ID"))
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// APIKey describes an API key. Only a salted hash of the secret is stored; the
+// plaintext key is returned once, when the key is created or rotated.
+type APIKey struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// Prefix is the public start of the key, so that keys can be told apart
+	// without revealing them.
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	// ReplacedBy is the ID of the key this one was rotated to.
+	ReplacedBy *int64 `json:"replaced_by,omitempty"`
+	Salt       []byte `json:"-"`
+	Hash       []byte `json:"-"`
+}
+
+// CreatedAPIKey is an API key along with its plaintext secret.
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type RotateAPIKeyRequest struct {
+	// Overlap is how long the old key keeps working, as a duration such as
+	// "24h". It defaults to 24 hours.
+	Overlap string `json:"overlap" example:"24h"`
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type AuditAction string
+
+const (
+	AuditCreate  AuditAction = "create"
+	AuditUpdate  AuditAction = "update"
+	AuditDelete  AuditAction = "delete"
+	AuditRestore AuditAction = "restore"
+	AuditPurge   AuditAction = "purge"
+)
+
+// UserAudit records a single change to a user. Before is null for created
+// users and After is null for purged users.
+type UserAudit struct {
+	ID        int64           `json:"id"`
+	UserID    int64           `json:"user_id"`
+	UserUUID  string          `json:"user_uuid"`
+	Actor     string          `json:"actor"`
+	Action    AuditAction     `json:"action"`
+	CreatedAt time.Time       `json:"created_at"`
+	Before    json.RawMessage `json:"before" swaggertype:"object"`
+	After     json.RawMessage `json:"after" swaggertype:"object"`
+}
+
+type AuditPage struct {
+	Entries    []UserAudit `json:"entries"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+type AuditListParams struct {
+	Limit    int         `form:"limit"`
+	Cursor   string      `form:"cursor"`
+	UserUUID string      `form:"user_uuid" binding:"omitempty,uuid"`
+	Actor    string      `form:"actor"`
+	Action   AuditAction `form:"action"`
+	// Since and Until are RFC 3339 timestamps bounding when the change was
+	// made, both exclusive.
+	Since time.Time `form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until time.Time `form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	// UserID limits the entries to a single user. It is set from the path
+	// rather than the query.
+	UserID int64 `form:"-"`
+}
@@ -0,0 +1,35 @@
+package model
+
+// The statuses of the service and its components.
+const (
+	HealthOK          = "ok"
+	HealthUnavailable = "unavailable"
+	// HealthDraining means the service is shutting down.
+	HealthDraining = "draining"
+)
+
+// The errors reported for a component that is unavailable. The underlying
+// error is only logged, as readiness is public.
+const (
+	HealthErrorTimeout     = "timeout"
+	HealthErrorUnreachable = "unreachable"
+)
+
+// ComponentHealth is the outcome of checking one dependency of the service.
+type ComponentHealth struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// ReadinessResponse is ok only when every component is.
+type ReadinessResponse struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+type VersionResponse struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"go_version"`
+}
@@ -1,8 +1,102 @@
 package model
 
+import "time"
+
 type User struct {
 	ID       int64  `json:"id"`
+	UUID     string `json:"uuid"`
 	Username string `json:"username"`
 	Email    string `json:"email"`
 	FullName string `json:"full_name"`
+	// CreatedAt and UpdatedAt are maintained by the database and serialized
+	// as RFC 3339.
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// DeletedAt is set while the user is soft deleted.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// Version is bumped on every update and exposed through the ETag header.
+	Version int64 `json:"-"`
+}
+
+type UserPage struct {
+	Users      []User `json:"users"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// ScoredUser is a search result. Score is the similarity to the search query,
+// between 0 and 1.
+type ScoredUser struct {
+	User
+	Score float64 `json:"score"`
+}
+
+type UserSearchParams struct {
+	Query string `form:"q"`
+	Limit int    `form:"limit"`
+}
+
+type UserSearchResult struct {
+	Users []ScoredUser `json:"users"`
+}
+
+type UserListParams struct {
+	Limit             int    `form:"limit"`
+	Cursor            string `form:"cursor"`
+	Username          string `form:"username"`
+	UsernamePrefix    string `form:"username_prefix"`
+	EmailDomain       string `form:"email_domain"`
+	EmailDomainPrefix string `form:"email_domain_prefix"`
+	FullName          string `form:"full_name"`
+	FullNamePrefix    string `form:"full_name_prefix"`
+	Sort              string `form:"sort"`
+	IncludeDeleted    bool   `form:"include_deleted"`
+	// CreatedAfter and UpdatedAfter are RFC 3339 timestamps. Only users
+	// created or updated strictly after them are listed.
+	CreatedAfter time.Time `form:"created_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	UpdatedAfter time.Time `form:"updated_after" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// BulkCreateResponse reports the outcome of a bulk create per submitted user,
+// in request order.
+type BulkCreateResponse struct {
+	Created int                `json:"created"`
+	Failed  int                `json:"failed"`
+	Results []BulkCreateResult `json:"results"`
+}
+
+// BulkCreateResult holds the HTTP status of a single user of a bulk create
+// along with the created user or the error.
+type BulkCreateResult struct {
+	Status int    `json:"status"`
+	User   *User  `json:"user,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportReport describes the outcome of a CSV import line by line.
+type ImportReport struct {
+	DryRun bool `json:"dry_run"`
+	// Valid counts the rows that were created, or would be on a dry run.
+	Valid  int          `json:"valid"`
+	Failed int          `json:"failed"`
+	Lines  []ImportLine `json:"lines"`
+}
+
+type ImportStatus string
+
+const (
+	ImportValid   ImportStatus = "valid"
+	ImportCreated ImportStatus = "created"
+	ImportFailed  ImportStatus = "failed"
+)
+
+type ImportLine struct {
+	Line     int          `json:"line"`
+	Username string       `json:"username"`
+	Status   ImportStatus `json:"status"`
+	User     *User        `json:"user,omitempty"`
+	Error    string       `json:"error,omitempty"`
+}
+
+type PurgeResult struct {
+	Purged int64 `json:"purged"`
 }
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrKeyNotFound  = errors.New("API key not found")
+	ErrKeyNotActive = errors.New("API key is revoked, expired or rotated")
+)
+
+type APIKeyRepository interface {
+	// Create stores key and fills in its ID and creation time.
+	Create(ctx context.Context, key *model.APIKey) error
+	GetByPrefix(ctx context.Context, prefix string) (*model.APIKey, error)
+	List(ctx context.Context) ([]model.APIKey, error)
+	// Revoke stops the key from working. Revoking a revoked key keeps its
+	// original revocation time.
+	Revoke(ctx context.Context, id int64) (*model.APIKey, error)
+	// Rotate stores replacement with the name, scopes and expiry of the key,
+	// and lets the key expire at overlapUntil. Only active keys can be
+	// rotated.
+	Rotate(ctx context.Context, id int64, replacement *model.APIKey, overlapUntil time.Time) error
+	// Touch records that the key has just been used. To spare a write per
+	// request, it only does so once a minute.
+	Touch(ctx context.Context, id int64) error
+}
+
+// apiKeyColumns is the column list selected and returned for every key, in the
+// order scanAPIKey expects.
+const apiKeyColumns = `id, name, prefix, salt, hash, scopes, created_at, expires_at, last_used_at, revoked_at, replaced_by`
+
+func scanAPIKey(row rowScanner, k *model.APIKey) error {
+	return row.Scan(&k.ID, &k.Name, &k.Prefix, &k.Salt, &k.Hash, pq.Array(&k.Scopes), &k.CreatedAt, &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt, &k.ReplacedBy)
+}
+
+type apiKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *model.APIKey) error {
+	return insertAPIKey(ctx, r.db, key)
+}
+
+func insertAPIKey(ctx context.Context, q queryer, key *model.APIKey) error {
+	return q.QueryRowContext(ctx, `INSERT INTO api_keys (name, prefix, salt, hash, scopes, expires_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		key.Name, key.Prefix, key.Salt, key.Hash, pq.Array(key.Scopes), key.ExpiresAt).Scan(&key.ID, &key.CreatedAt)
+}
+
+func (r *apiKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*model.APIKey, error) {
+	var k model.APIKey
+	if err := scanAPIKey(r.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE prefix = $1`, prefix), &k); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrKeyNotFound
+		}
+		return nil, err
+	}
+	return &k, nil
+}
+
+func (r *apiKeyRepository) List(ctx context.Context) ([]model.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []model.APIKey
+	for rows.Next() {
+		var k model.APIKey
+		if err := scanAPIKey(rows, &k); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, rows.Close()
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id int64) (*model.APIKey, error) {
+	var k model.APIKey
+	if err := scanAPIKey(r.db.QueryRowContext(ctx, `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, now()) WHERE id = $1 RETURNING `+apiKeyColumns, id), &k); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrKeyNotFound
+		}
+		return nil, err
+	}
+	return &k, nil
+}
+
+func (r *apiKeyRepository) Rotate(ctx context.Context, id int64, replacement *model.APIKey, overlapUntil time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var old model.APIKey
+	if err := scanAPIKey(tx.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1 FOR UPDATE`, id), &old); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrKeyNotFound
+		}
+		return err
+	}
+	if old.RevokedAt != nil || old.ReplacedBy != nil || (old.ExpiresAt != nil && !old.ExpiresAt.After(time.Now())) {
+		return ErrKeyNotActive
+	}
+
+	replacement.Name, replacement.Scopes, replacement.ExpiresAt = old.Name, old.Scopes, old.ExpiresAt
+	if err := insertAPIKey(ctx, tx, replacement); err != nil {
+		return err
+	}
+	if old.ExpiresAt == nil || overlapUntil.Before(*old.ExpiresAt) {
+		old.ExpiresAt = &overlapUntil
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE api_keys SET expires_at = $2, replaced_by = $3 WHERE id = $1`, id, old.ExpiresAt, replacement.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *apiKeyRepository) Touch(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = now() WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')`, id)
+	return err
+}
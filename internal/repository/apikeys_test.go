@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var apiKeyRowColumns = []string{"id", "name", "prefix", "salt", "hash", "scopes", "created_at", "expires_at", "last_used_at", "revoked_at", "replaced_by"}
+
+func expectLockAPIKey(mock sqlmock.Sqlmock, id int64, expiresAt, revokedAt any) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = $1 FOR UPDATE`)).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(apiKeyRowColumns).
+			AddRow(id, "crm", "cruder_000000000001", []byte("salt"), []byte("hash"), "{users:read,users:write}", createdAt, expiresAt, nil, revokedAt, nil))
+}
+
+func TestAPIKeyRotate_Success(t *testing.T) {
+	// Given: an active key that expires in a week
+	db, mock := newMockDB(t)
+	repo := NewAPIKeyRepository(db)
+	expiresAt := time.Now().Add(7 * 24 * time.Hour)
+	overlapUntil := time.Now().Add(time.Hour)
+
+	mock.ExpectBegin()
+	expectLockAPIKey(mock, 1, expiresAt, nil)
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO api_keys (name, prefix, salt, hash, scopes, expires_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`)).
+		WithArgs("crm", "cruder_000000000002", []byte("salt2"), []byte("hash2"), "{\"users:read\",\"users:write\"}", expiresAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(2, createdAt))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE api_keys SET expires_at = $2, replaced_by = $3 WHERE id = $1`)).
+		WithArgs(int64(1), overlapUntil, int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// When: rotating the key with an hour of overlap
+	replacement := &model.APIKey{Prefix: "cruder_000000000002", Salt: []byte("salt2"), Hash: []byte("hash2")}
+	err := repo.Rotate(context.Background(), 1, replacement, overlapUntil)
+
+	// Then: the replacement should inherit the key and the old key should expire after the overlap
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), replacement.ID)
+	assert.Equal(t, "crm", replacement.Name)
+	assert.Equal(t, []string{"users:read", "users:write"}, replacement.Scopes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIKeyRotate_RevokedKey(t *testing.T) {
+	// Given: a revoked key
+	db, mock := newMockDB(t)
+	repo := NewAPIKeyRepository(db)
+
+	mock.ExpectBegin()
+	expectLockAPIKey(mock, 1, nil, createdAt)
+	mock.ExpectRollback()
+
+	// When: rotating the key
+	err := repo.Rotate(context.Background(), 1, &model.APIKey{}, time.Now())
+
+	// Then: ErrKeyNotActive should be returned and nothing written
+	assert.ErrorIs(t, err, ErrKeyNotActive)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIKeyGetByPrefix_NotFound(t *testing.T) {
+	// Given: no key with the prefix
+	db, mock := newMockDB(t)
+	repo := NewAPIKeyRepository(db)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT ` + apiKeyColumns + ` FROM api_keys WHERE prefix = $1`)).
+		WithArgs("cruder_000000000009").
+		WillReturnRows(sqlmock.NewRows(apiKeyRowColumns))
+
+	// When: looking the prefix up
+	key, err := repo.GetByPrefix(context.Background(), "cruder_000000000009")
+
+	// Then: ErrKeyNotFound should be returned
+	assert.Nil(t, key)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/auth"
+	"cruder/internal/model"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type AuditRepository interface {
+	// List returns the audit entries matching opts, newest first.
+	List(ctx context.Context, opts AuditListOptions) ([]model.UserAudit, error)
+}
+
+// AuditListOptions describes a single keyset page of audit entries. Empty
+// fields are ignored.
+type AuditListOptions struct {
+	Limit    int
+	UserID   int64
+	UserUUID string
+	Actor    string
+	Action   model.AuditAction
+	// Since and Until are exclusive bounds on when the change was made.
+	Since time.Time
+	Until time.Time
+	// BeforeID continues after the last entry of the previous page.
+	BeforeID int64
+}
+
+const auditColumns = `id, user_id, user_uuid, actor, action, created_at, before, after`
+
+type auditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+func (r *auditRepository) List(ctx context.Context, opts AuditListOptions) ([]model.UserAudit, error) {
+	query, args := buildAuditQuery(opts)
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []model.UserAudit
+	for rows.Next() {
+		var (
+			e             model.UserAudit
+			before, after []byte
+		)
+		if err := rows.Scan(&e.ID, &e.UserID, &e.UserUUID, &e.Actor, &e.Action, &e.CreatedAt, &before, &after); err != nil {
+			return nil, err
+		}
+		e.Before, e.After = before, after
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, rows.Close()
+}
+
+func buildAuditQuery(opts AuditListOptions) (string, []any) {
+	var (
+		where []string
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if opts.UserID != 0 {
+		where = append(where, "user_id = "+arg(opts.UserID))
+	}
+	if opts.UserUUID != "" {
+		where = append(where, "user_uuid = "+arg(opts.UserUUID))
+	}
+	if opts.Actor != "" {
+		where = append(where, "actor = "+arg(opts.Actor))
+	}
+	if opts.Action != "" {
+		where = append(where, "action = "+arg(string(opts.Action)))
+	}
+	if !opts.Since.IsZero() {
+		where = append(where, "created_at > "+arg(opts.Since))
+	}
+	if !opts.Until.IsZero() {
+		where = append(where, "created_at < "+arg(opts.Until))
+	}
+	if opts.BeforeID != 0 {
+		where = append(where, "id < "+arg(opts.BeforeID))
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM user_audit`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT " + arg(opts.Limit)
+	return query, args
+}
+
+// writeAudit records a change to a user made by the actor of ctx. Either
+// snapshot may be nil.
+func writeAudit(ctx context.Context, q queryer, action model.AuditAction, before, after *model.User) error {
+	subject := after
+	if subject == nil {
+		subject = before
+	}
+
+	snapshot := func(u *model.User) (sql.NullString, error) {
+		if u == nil {
+			return sql.NullString{}, nil
+		}
+		raw, err := json.Marshal(u)
+		return sql.NullString{String: string(raw), Valid: true}, err
+	}
+	beforeJSON, err := snapshot(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := snapshot(after)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.ExecContext(ctx, `INSERT INTO user_audit (user_id, user_uuid, actor, action, before, after) VALUES ($1, $2, $3, $4, $5, $6)`,
+		subject.ID, subject.UUID, auth.Actor(ctx), string(action), beforeJSON, afterJSON)
+	return err
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditList_Filters(t *testing.T) {
+	// Given: a mock db expecting a filtered page of audit entries
+	db, mock := newMockDB(t)
+	repo := NewAuditRepository(db)
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "user_id", "user_uuid", "actor", "action", "created_at", "before", "after"}).
+		AddRow(7, 1, johnUUID, "crm", "update", createdAt, []byte(`{"username":"john"}`), []byte(`{"username":"john_doe"}`)).
+		AddRow(3, 1, johnUUID, "crm", "create", createdAt, nil, []byte(`{"username":"john"}`))
+	mock.ExpectQuery(`SELECT id, user_id, user_uuid, actor, action, created_at, before, after FROM user_audit `+
+		`WHERE user_id = \$1 AND actor = \$2 AND created_at > \$3 AND id < \$4 ORDER BY id DESC LIMIT \$5`).
+		WithArgs(int64(1), "crm", since, int64(10), 20).
+		WillReturnRows(rows)
+
+	// When: calling List with user, actor, time and keyset filters
+	entries, err := repo.List(context.Background(), AuditListOptions{Limit: 20, UserID: 1, Actor: "crm", Since: since, BeforeID: 10})
+
+	// Then: the entries should be returned newest first with their snapshots
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, model.AuditUpdate, entries[0].Action)
+	assert.JSONEq(t, `{"username":"john"}`, string(entries[0].Before))
+	assert.Nil(t, entries[1].Before)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
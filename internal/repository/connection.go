@@ -1,10 +1,18 @@
 package repository
 
 import (
+	"context"
+	"cruder/internal/tracing"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"log/slog"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type DatabaseConnection interface {
@@ -19,17 +27,120 @@ func (p *PostgresConnection) DB() *sql.DB {
 	return p.db
 }
 
-func NewPostgresConnection(dsn string) (*PostgresConnection, error) {
-	db, err := sql.Open("postgres", dsn)
+// PoolOptions tunes the connection pool. Zero values keep the database/sql
+// defaults.
+type PoolOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// dsnConnector opens every connection with the DSN as it is at the time, so
+// that a rotated password is picked up by new connections.
+type dsnConnector struct {
+	dsn func() (string, error)
+}
+
+// errDSN marks a connection string that cannot be read or parsed, which no
+// amount of waiting fixes.
+var errDSN = errors.New("invalid database connection string")
+
+func (c dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	dsn, err := c.dsn()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errDSN, err)
+	}
+	connector, err := pq.NewConnector(dsn)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, fmt.Errorf("%w: %w", errDSN, err)
 	}
+	return connector.Connect(ctx)
+}
+
+func (dsnConnector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
 
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
+// RetryOptions controls how long startup waits for the database.
+type RetryOptions struct {
+	// Timeout is the total time given to connect, across every attempt.
+	Timeout time.Duration
+	// InitialBackoff is the wait after the first failed attempt. It doubles
+	// after each further attempt, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// NewPostgresConnection connects to the database, tracing every statement
+// with tp. dsn is called for every new connection. A database that is not up
+// yet is retried with exponential backoff until retry.Timeout has passed or
+// ctx is done.
+func NewPostgresConnection(ctx context.Context, dsn func() (string, error), pool PoolOptions, retry RetryOptions, tp trace.TracerProvider, logger *slog.Logger) (*PostgresConnection, error) {
+	db := sql.OpenDB(tracing.WrapConnector(dsnConnector{dsn: dsn}, tp, semconv.DBSystemNamePostgreSQL))
+	if pool.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(pool.MaxOpenConns)
+	}
+	if pool.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+
+	if err := waitForDatabase(ctx, db, retry, logger); err != nil {
+		_ = db.Close()
+		return nil, err
 	}
 
 	return &PostgresConnection{
 		db: db,
 	}, nil
 }
+
+// waitForDatabase pings db until it answers, logging every attempt.
+func waitForDatabase(ctx context.Context, db *sql.DB, retry RetryOptions, logger *slog.Logger) error {
+	ctx, cancel := context.WithTimeout(ctx, retry.Timeout)
+	defer cancel()
+
+	backoff := retry.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			logger.Info("connected to database", slog.Int("attempt", attempt))
+			return nil
+		}
+		if ctx.Err() != nil || !retryable(err) {
+			return fmt.Errorf("failed to connect to database after %d attempts: %w", attempt, err)
+		}
+		logger.Warn("failed to connect to database, retrying",
+			slog.Int("attempt", attempt),
+			slog.Duration("backoff", backoff),
+			slog.Any("err", err))
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to connect to database after %d attempts: %w", attempt, err)
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, retry.MaxBackoff)
+	}
+}
+
+// retryable tells whether err may go away once the database is up. A bad
+// connection string or an answer from the server, such as wrong credentials
+// or an unknown database, is final; connection failures and a server that is
+// still starting or out of connections are not.
+func retryable(err error) bool {
+	if errors.Is(err, errDSN) {
+		return false
+	}
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return true
+	}
+	switch pqErr.Code.Name() {
+	case "cannot_connect_now", "too_many_connections", "admin_shutdown", "crash_shutdown":
+		return true
+	}
+	return pqErr.Code.Class() == "08"
+}
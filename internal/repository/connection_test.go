@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForDatabase_RetriesWithBackoff(t *testing.T) {
+	// Given: a database that refuses the first two connections
+	db, mock, _ := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	defer db.Close()
+	refused := errors.New("connection refused")
+	mock.ExpectPing().WillReturnError(refused)
+	mock.ExpectPing().WillReturnError(refused)
+	mock.ExpectPing()
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+
+	// When: waiting for it
+	start := time.Now()
+	err := waitForDatabase(context.Background(), db, RetryOptions{Timeout: time.Second, InitialBackoff: 10 * time.Millisecond, MaxBackoff: time.Second}, logger)
+
+	// Then: it should connect on the third attempt, backing off in between
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+	assert.Equal(t, 2, strings.Count(logs.String(), "failed to connect to database, retrying"))
+	assert.Contains(t, logs.String(), "backoff=20ms")
+	assert.Contains(t, logs.String(), `msg="connected to database" attempt=3`)
+}
+
+func TestWaitForDatabase_GivesUpAtDeadline(t *testing.T) {
+	// Given: a database that never comes up
+	db, mock, _ := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	defer db.Close()
+	for range 10 {
+		mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	}
+
+	// When: waiting for it
+	err := waitForDatabase(context.Background(), db, RetryOptions{Timeout: 50 * time.Millisecond, InitialBackoff: 20 * time.Millisecond, MaxBackoff: 20 * time.Millisecond}, slog.New(slog.DiscardHandler))
+
+	// Then: it should give up once the deadline passes
+	assert.ErrorContains(t, err, "failed to connect to database after")
+}
+
+func TestWaitForDatabase_FailsFastOnRejectedCredentials(t *testing.T) {
+	// Given: a database that is up but rejects the password
+	db, mock, _ := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	defer db.Close()
+	mock.ExpectPing().WillReturnError(&pq.Error{Code: "28P01", Message: "password authentication failed"})
+
+	// When: waiting for it
+	err := waitForDatabase(context.Background(), db, RetryOptions{Timeout: time.Second, InitialBackoff: 10 * time.Millisecond, MaxBackoff: time.Second}, slog.New(slog.DiscardHandler))
+
+	// Then: it should give up after the first attempt
+	assert.ErrorContains(t, err, "failed to connect to database after 1 attempts")
+	assert.ErrorContains(t, err, "password authentication failed")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWaitForDatabase_FailsFastOnInvalidDSN(t *testing.T) {
+	// Given: a connection string whose secret cannot be read
+	db := sql.OpenDB(dsnConnector{dsn: func() (string, error) { return "", errors.New("secret POSTGRES_PASSWORD is not set") }})
+	defer db.Close()
+
+	// When: waiting for the database
+	err := waitForDatabase(context.Background(), db, RetryOptions{Timeout: time.Second, InitialBackoff: 10 * time.Millisecond, MaxBackoff: time.Second}, slog.New(slog.DiscardHandler))
+
+	// Then: it should give up after the first attempt
+	assert.ErrorIs(t, err, errDSN)
+	assert.ErrorContains(t, err, "failed to connect to database after 1 attempts")
+}
+
+func TestRetryable(t *testing.T) {
+	// Given: the errors a ping may fail with
+	tests := map[string]struct {
+		err  error
+		want bool
+	}{
+		"connection refused":    {errors.New("dial tcp: connection refused"), true},
+		"starting up":           {&pq.Error{Code: "57P03"}, true},
+		"too many connections":  {&pq.Error{Code: "53300"}, true},
+		"connection failure":    {&pq.Error{Code: "08006"}, true},
+		"invalid password":      {&pq.Error{Code: "28P01"}, false},
+		"invalid authorization": {&pq.Error{Code: "28000"}, false},
+		"unknown database":      {&pq.Error{Code: "3D000"}, false},
+		"invalid dsn":           {fmt.Errorf("%w: missing secret", errDSN), false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// When: deciding whether to retry
+			got := retryable(tt.err)
+
+			// Then: only connection failures should be retried
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
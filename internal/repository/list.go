@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"cruder/internal/model"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ListOptions describes a single keyset page of users.
+type ListOptions struct {
+	// Limit caps the number of users. Zero lists all of them.
+	Limit  int
+	Filter UserFilter
+	// Sort must end with a unique field (id) so that the keyset is total.
+	Sort []SortField
+	// After holds the Sort values of the last row of the previous page,
+	// one per Sort field. An empty After starts from the first page.
+	After []any
+}
+
+// UserFilter narrows down the listed users. Empty fields are ignored.
+type UserFilter struct {
+	Username          string
+	UsernamePrefix    string
+	EmailDomain       string
+	EmailDomainPrefix string
+	FullName          string
+	FullNamePrefix    string
+	// CreatedAfter and UpdatedAfter are exclusive lower bounds. Zero times
+	// are ignored.
+	CreatedAfter time.Time
+	UpdatedAfter time.Time
+	// IncludeDeleted also lists soft deleted users.
+	IncludeDeleted bool
+}
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// userSortFields is the server-side whitelist of fields users can be sorted
+// by, mapped to their columns and to the value a page cursor keeps for them.
+var userSortFields = map[string]struct {
+	column string
+	value  func(u model.User) string
+}{
+	"id":         {"id", func(u model.User) string { return strconv.FormatInt(u.ID, 10) }},
+	"username":   {"username", func(u model.User) string { return u.Username }},
+	"email":      {"email", func(u model.User) string { return u.Email }},
+	"full_name":  {"full_name", func(u model.User) string { return u.FullName }},
+	"created_at": {"created_at", func(u model.User) string { return u.CreatedAt.Format(time.RFC3339Nano) }},
+	"updated_at": {"updated_at", func(u model.User) string { return u.UpdatedAt.Format(time.RFC3339Nano) }},
+}
+
+func IsSortableUserField(field string) bool {
+	_, ok := userSortFields[field]
+	return ok
+}
+
+// UserSortValue returns the value of the sort field of u, as a page cursor
+// keeps it.
+func UserSortValue(u model.User, field string) (string, bool) {
+	f, ok := userSortFields[field]
+	if !ok {
+		return "", false
+	}
+	return f.value(u), true
+}
+
+func buildListQuery(opts ListOptions) (string, []any, error) {
+	var (
+		where []string
+		args  []any
+	)
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	f := opts.Filter
+	if !f.IncludeDeleted {
+		where = append(where, "deleted_at IS NULL")
+	}
+	if f.Username != "" {
+		where = append(where, "username = "+arg(f.Username))
+	}
+	if f.UsernamePrefix != "" {
+		where = append(where, "username LIKE "+arg(likePrefix(f.UsernamePrefix)))
+	}
+	if f.EmailDomain != "" {
+		where = append(where, "lower(split_part(email, '@', 2)) = "+arg(strings.ToLower(f.EmailDomain)))
+	}
+	if f.EmailDomainPrefix != "" {
+		where = append(where, "lower(split_part(email, '@', 2)) LIKE "+arg(likePrefix(strings.ToLower(f.EmailDomainPrefix))))
+	}
+	if f.FullName != "" {
+		where = append(where, "full_name = "+arg(f.FullName))
+	}
+	if f.FullNamePrefix != "" {
+		where = append(where, "full_name LIKE "+arg(likePrefix(f.FullNamePrefix)))
+	}
+	if !f.CreatedAfter.IsZero() {
+		where = append(where, "created_at > "+arg(f.CreatedAfter))
+	}
+	if !f.UpdatedAfter.IsZero() {
+		where = append(where, "updated_at > "+arg(f.UpdatedAfter))
+	}
+
+	columns := make([]string, len(opts.Sort))
+	order := make([]string, len(opts.Sort))
+	for i, s := range opts.Sort {
+		field, ok := userSortFields[s.Field]
+		if !ok {
+			return "", nil, fmt.Errorf("unsupported sort field %q", s.Field)
+		}
+		column := field.column
+		columns[i] = column
+		order[i] = column + " ASC"
+		if s.Desc {
+			order[i] = column + " DESC"
+		}
+	}
+
+	if len(opts.After) > 0 {
+		if len(opts.After) != len(opts.Sort) {
+			return "", nil, fmt.Errorf("keyset has %d values for %d sort fields", len(opts.After), len(opts.Sort))
+		}
+		// (a > $1) OR (a = $1 AND b < $2) OR ... expanded per field, since
+		// a row comparison cannot mix ascending and descending columns.
+		placeholders := make([]string, len(opts.After))
+		for i, v := range opts.After {
+			placeholders[i] = arg(v)
+		}
+		var keyset []string
+		for i := range opts.Sort {
+			var terms []string
+			for j := 0; j < i; j++ {
+				terms = append(terms, columns[j]+" = "+placeholders[j])
+			}
+			op := " > "
+			if opts.Sort[i].Desc {
+				op = " < "
+			}
+			terms = append(terms, columns[i]+op+placeholders[i])
+			keyset = append(keyset, "("+strings.Join(terms, " AND ")+")")
+		}
+		where = append(where, "("+strings.Join(keyset, " OR ")+")")
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	if len(order) > 0 {
+		query += " ORDER BY " + strings.Join(order, ", ")
+	}
+	if opts.Limit > 0 {
+		query += " LIMIT " + arg(opts.Limit)
+	}
+
+	return query, args, nil
+}
+
+// likePrefix escapes LIKE wildcards in s and turns it into a prefix pattern.
+func likePrefix(s string) string {
+	return likeEscaper.Replace(s) + "%"
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// queryObserver records how long the methods of one repository take.
+type queryObserver struct {
+	durations  *prometheus.HistogramVec
+	repository string
+}
+
+// observe records a method call that started at start and ended with *err.
+// It is meant to be deferred.
+func (o queryObserver) observe(method string, start time.Time, err *error) {
+	outcome := "ok"
+	if *err != nil {
+		outcome = "error"
+	}
+	o.durations.WithLabelValues(o.repository, method, outcome).Observe(time.Since(start).Seconds())
+}
+
+type instrumentedUserRepository struct {
+	queryObserver
+	next UserRepository
+}
+
+// InstrumentUserRepository records the duration of every call to repo in
+// durations.
+func InstrumentUserRepository(repo UserRepository, durations *prometheus.HistogramVec) UserRepository {
+	return &instrumentedUserRepository{queryObserver{durations, "users"}, repo}
+}
+
+func (r *instrumentedUserRepository) GetAll(ctx context.Context, opts ListOptions) (_ []model.User, err error) {
+	defer r.observe("GetAll", time.Now(), &err)
+	return r.next.GetAll(ctx, opts)
+}
+
+func (r *instrumentedUserRepository) Stream(ctx context.Context, opts ListOptions, fn func(*model.User) error) (err error) {
+	defer r.observe("Stream", time.Now(), &err)
+	return r.next.Stream(ctx, opts, fn)
+}
+
+func (r *instrumentedUserRepository) Search(ctx context.Context, query string, limit int) (_ []model.ScoredUser, err error) {
+	defer r.observe("Search", time.Now(), &err)
+	return r.next.Search(ctx, query, limit)
+}
+
+func (r *instrumentedUserRepository) GetByUsername(ctx context.Context, username string, includeDeleted bool) (_ *model.User, err error) {
+	defer r.observe("GetByUsername", time.Now(), &err)
+	return r.next.GetByUsername(ctx, username, includeDeleted)
+}
+
+func (r *instrumentedUserRepository) GetByID(ctx context.Context, id int64, includeDeleted bool) (_ *model.User, err error) {
+	defer r.observe("GetByID", time.Now(), &err)
+	return r.next.GetByID(ctx, id, includeDeleted)
+}
+
+func (r *instrumentedUserRepository) GetByUUID(ctx context.Context, uuid string, includeDeleted bool) (_ *model.User, err error) {
+	defer r.observe("GetByUUID", time.Now(), &err)
+	return r.next.GetByUUID(ctx, uuid, includeDeleted)
+}
+
+func (r *instrumentedUserRepository) Create(ctx context.Context, user *model.User) (_ *model.User, err error) {
+	defer r.observe("Create", time.Now(), &err)
+	return r.next.Create(ctx, user)
+}
+
+func (r *instrumentedUserRepository) CreateMany(ctx context.Context, users []*model.User, atomic bool) (_ []error, err error) {
+	defer r.observe("CreateMany", time.Now(), &err)
+	return r.next.CreateMany(ctx, users, atomic)
+}
+
+func (r *instrumentedUserRepository) Taken(ctx context.Context, usernames, emails []string) (_, _ []string, err error) {
+	defer r.observe("Taken", time.Now(), &err)
+	return r.next.Taken(ctx, usernames, emails)
+}
+
+func (r *instrumentedUserRepository) Delete(ctx context.Context, id int64, version int64) (err error) {
+	defer r.observe("Delete", time.Now(), &err)
+	return r.next.Delete(ctx, id, version)
+}
+
+func (r *instrumentedUserRepository) Update(ctx context.Context, user *model.User) (_ *model.User, err error) {
+	defer r.observe("Update", time.Now(), &err)
+	return r.next.Update(ctx, user)
+}
+
+func (r *instrumentedUserRepository) Restore(ctx context.Context, id int64) (_ *model.User, err error) {
+	defer r.observe("Restore", time.Now(), &err)
+	return r.next.Restore(ctx, id)
+}
+
+func (r *instrumentedUserRepository) Purge(ctx context.Context, deletedBefore time.Time) (_ int64, err error) {
+	defer r.observe("Purge", time.Now(), &err)
+	return r.next.Purge(ctx, deletedBefore)
+}
+
+type instrumentedAuditRepository struct {
+	queryObserver
+	next AuditRepository
+}
+
+// InstrumentAuditRepository records the duration of every call to repo in
+// durations.
+func InstrumentAuditRepository(repo AuditRepository, durations *prometheus.HistogramVec) AuditRepository {
+	return &instrumentedAuditRepository{queryObserver{durations, "audit"}, repo}
+}
+
+func (r *instrumentedAuditRepository) List(ctx context.Context, opts AuditListOptions) (_ []model.UserAudit, err error) {
+	defer r.observe("List", time.Now(), &err)
+	return r.next.List(ctx, opts)
+}
+
+type instrumentedAPIKeyRepository struct {
+	queryObserver
+	next APIKeyRepository
+}
+
+// InstrumentAPIKeyRepository records the duration of every call to repo in
+// durations.
+func InstrumentAPIKeyRepository(repo APIKeyRepository, durations *prometheus.HistogramVec) APIKeyRepository {
+	return &instrumentedAPIKeyRepository{queryObserver{durations, "api_keys"}, repo}
+}
+
+func (r *instrumentedAPIKeyRepository) Create(ctx context.Context, key *model.APIKey) (err error) {
+	defer r.observe("Create", time.Now(), &err)
+	return r.next.Create(ctx, key)
+}
+
+func (r *instrumentedAPIKeyRepository) GetByPrefix(ctx context.Context, prefix string) (_ *model.APIKey, err error) {
+	defer r.observe("GetByPrefix", time.Now(), &err)
+	return r.next.GetByPrefix(ctx, prefix)
+}
+
+func (r *instrumentedAPIKeyRepository) List(ctx context.Context) (_ []model.APIKey, err error) {
+	defer r.observe("List", time.Now(), &err)
+	return r.next.List(ctx)
+}
+
+func (r *instrumentedAPIKeyRepository) Revoke(ctx context.Context, id int64) (_ *model.APIKey, err error) {
+	defer r.observe("Revoke", time.Now(), &err)
+	return r.next.Revoke(ctx, id)
+}
+
+func (r *instrumentedAPIKeyRepository) Rotate(ctx context.Context, id int64, replacement *model.APIKey, overlapUntil time.Time) (err error) {
+	defer r.observe("Rotate", time.Now(), &err)
+	return r.next.Rotate(ctx, id, replacement, overlapUntil)
+}
+
+func (r *instrumentedAPIKeyRepository) Touch(ctx context.Context, id int64) (err error) {
+	defer r.observe("Touch", time.Now(), &err)
+	return r.next.Touch(ctx, id)
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrumentedRepository_ObservesOutcome(t *testing.T) {
+	// Given: an instrumented audit repository whose second query fails
+	db, mock := newMockDB(t)
+	durations := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "query_duration_seconds"}, []string{"repository", "method", "outcome"})
+	repo := InstrumentAuditRepository(NewAuditRepository(db), durations)
+	mock.ExpectQuery(`SELECT .* FROM user_audit`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT .* FROM user_audit`).WillReturnError(errors.New("connection reset"))
+
+	// When: List is called twice
+	_, okErr := repo.List(context.Background(), AuditListOptions{Limit: 1})
+	_, failErr := repo.List(context.Background(), AuditListOptions{Limit: 1})
+
+	// Then: each call should be observed once, labelled by its outcome
+	assert.NoError(t, okErr)
+	assert.Error(t, failErr)
+	for _, outcome := range []string{"ok", "error"} {
+		var m dto.Metric
+		assert.NoError(t, durations.WithLabelValues("audit", "List", outcome).(prometheus.Histogram).Write(&m))
+		assert.Equal(t, uint64(1), m.GetHistogram().GetSampleCount(), outcome)
+	}
+}
@@ -1,13 +1,24 @@
 package repository
 
-import "database/sql"
+import (
+	"database/sql"
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
 
 type Repository struct {
-	Users UserRepository
+	Users   UserRepository
+	Audit   AuditRepository
+	APIKeys APIKeyRepository
 }
 
-func NewRepository(db *sql.DB) *Repository {
+// NewRepository creates the repositories, recording the duration of each of
+// their methods in queryDurations.
+func NewRepository(db *sql.DB, logger *slog.Logger, queryDurations *prometheus.HistogramVec) *Repository {
 	return &Repository{
-		Users: NewUserRepository(db),
+		Users:   InstrumentUserRepository(NewUserRepository(db, logger), queryDurations),
+		Audit:   InstrumentAuditRepository(NewAuditRepository(db), queryDurations),
+		APIKeys: InstrumentAPIKeyRepository(NewAPIKeyRepository(db), queryDurations),
 	}
 }
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+)
+
+// userColumns is the column list selected and returned for every user, in the
+// order scanUser expects.
+const userColumns = `id, uuid, username, email, full_name, created_at, updated_at, deleted_at, version`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// scanUser scans the userColumns of row into u, followed by any extra
+// columns.
+func scanUser(row rowScanner, u *model.User, extra ...any) error {
+	dest := []any{&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName, &u.CreatedAt, &u.UpdatedAt, &u.DeletedAt, &u.Version}
+	return row.Scan(append(dest, extra...)...)
+}
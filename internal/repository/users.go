@@ -2,61 +2,154 @@ package repository
 
 import (
 	"context"
+	"cruder/internal/auth"
 	"cruder/internal/model"
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/lib/pq"
 )
 
+// UserRepository stores users. Every change to a user is recorded in the
+// user_audit table in the same transaction as the change itself, attributed to
+// the actor of the context.
 type UserRepository interface {
-	GetAll() ([]model.User, error)
-	GetByUsername(username string) (*model.User, error)
-	GetByID(id int64) (*model.User, error)
-	Create(user *model.User) (*model.User, error)
-	Delete(id int64) error
-	Update(user *model.User) (*model.User, error)
+	GetAll(ctx context.Context, opts ListOptions) ([]model.User, error)
+	// Stream calls fn for every user GetAll would return, as the rows are
+	// read, without holding them all in memory. It stops at the first error
+	// returned by fn.
+	Stream(ctx context.Context, opts ListOptions, fn func(*model.User) error) error
+	// Search returns up to limit active users whose username, email or full
+	// name are similar to query, best matches first.
+	Search(ctx context.Context, query string, limit int) ([]model.ScoredUser, error)
+	// The single user getters skip soft deleted users unless includeDeleted
+	// is set.
+	GetByUsername(ctx context.Context, username string, includeDeleted bool) (*model.User, error)
+	GetByID(ctx context.Context, id int64, includeDeleted bool) (*model.User, error)
+	GetByUUID(ctx context.Context, uuid string, includeDeleted bool) (*model.User, error)
+	Create(ctx context.Context, user *model.User) (*model.User, error)
+	// CreateMany inserts the users in order and returns the error of each
+	// insert, if any. With atomic set the users are inserted in a single
+	// transaction that is only committed when every insert succeeds; users
+	// that were inserted before the rollback get ErrRolledBack.
+	CreateMany(ctx context.Context, users []*model.User, atomic bool) ([]error, error)
+	// Taken returns which of the given usernames and emails already belong to
+	// an active user. Those of soft deleted users can be reused.
+	Taken(ctx context.Context, usernames, emails []string) (takenUsernames, takenEmails []string, err error)
+	// Delete soft deletes the user. Delete and Update only touch the row
+	// while its version matches. A zero version skips the check.
+	Delete(ctx context.Context, id int64, version int64) error
+	Update(ctx context.Context, user *model.User) (*model.User, error)
+	Restore(ctx context.Context, id int64) (*model.User, error)
+	// Purge permanently removes users soft deleted before the given time and
+	// returns how many were removed.
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
 }
 
 type userRepository struct {
-	db *sql.DB
+	db     *sql.DB
+	logger *slog.Logger
 }
 
-func NewUserRepository(db *sql.DB) UserRepository {
-	return &userRepository{db: db}
+// NewUserRepository creates the user repository. Its logs carry the request ID
+// of the context when logger has a logging.ContextHandler.
+func NewUserRepository(db *sql.DB, logger *slog.Logger) UserRepository {
+	return &userRepository{db: db, logger: logger}
 }
 
-func (r *userRepository) GetAll() ([]model.User, error) {
-	rows, err := r.db.QueryContext(context.Background(), `SELECT id, username, email, full_name FROM users`)
-	if err != nil {
+func (r *userRepository) GetAll(ctx context.Context, opts ListOptions) ([]model.User, error) {
+	var users []model.User
+	if err := r.Stream(ctx, opts, func(u *model.User) error {
+		users = append(users, *u)
+		return nil
+	}); err != nil {
 		return nil, err
 	}
+	return users, nil
+}
+
+func (r *userRepository) Stream(ctx context.Context, opts ListOptions, fn func(*model.User) error) error {
+	query, args, err := buildListQuery(opts)
+	if err != nil {
+		return err
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
 
-	var users []model.User
 	for rows.Next() {
 		var u model.User
-		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.FullName); err != nil {
-			return nil, err
+		if err := scanUser(rows, &u); err != nil {
+			return err
+		}
+		if err := fn(&u); err != nil {
+			return err
 		}
-		users = append(users, u)
 	}
 
 	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return rows.Close()
+}
+
+// searchQuery ranks users by the trigram word similarity of the query to their
+// best matching field. The <% operators let the GIN trigram indexes narrow
+// down the candidates before they are scored.
+const searchQuery = `SELECT ` + userColumns + `, score FROM (
+	SELECT *, GREATEST(word_similarity($1, username), word_similarity($1, email), word_similarity($1, full_name)) AS score
+	FROM users
+	WHERE deleted_at IS NULL AND ($1 <% username OR $1 <% email OR $1 <% full_name)
+) AS matches ORDER BY score DESC, id ASC LIMIT $2`
+
+func (r *userRepository) Search(ctx context.Context, query string, limit int) ([]model.ScoredUser, error) {
+	rows, err := r.db.QueryContext(ctx, searchQuery, query, limit)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	if err := rows.Close(); err != nil {
+	var users []model.ScoredUser
+	for rows.Next() {
+		var u model.ScoredUser
+		if err := scanUser(rows, &u.User, &u.Score); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return users, nil
+	return users, rows.Close()
 }
 
-func (r *userRepository) GetByUsername(username string) (*model.User, error) {
+func (r *userRepository) GetByUsername(ctx context.Context, username string, includeDeleted bool) (*model.User, error) {
+	// A username can belong to one active user and any number of deleted
+	// ones; the active user wins, then the most recently deleted one.
+	return r.getOne(ctx, `SELECT `+userColumns+` FROM users WHERE username = $1 AND ($2 OR deleted_at IS NULL)
+		ORDER BY deleted_at DESC NULLS FIRST LIMIT 1`, username, includeDeleted)
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int64, includeDeleted bool) (*model.User, error) {
+	return r.getOne(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1 AND ($2 OR deleted_at IS NULL)`, id, includeDeleted)
+}
+
+func (r *userRepository) GetByUUID(ctx context.Context, uuid string, includeDeleted bool) (*model.User, error) {
+	return r.getOne(ctx, `SELECT `+userColumns+` FROM users WHERE uuid = $1 AND ($2 OR deleted_at IS NULL)`, uuid, includeDeleted)
+}
+
+func (r *userRepository) getOne(ctx context.Context, query string, args ...any) (*model.User, error) {
 	var u model.User
-	if err := r.db.QueryRowContext(context.Background(), `SELECT id, username, email, full_name FROM users WHERE username = $1`, username).
-		Scan(&u.ID, &u.Username, &u.Email, &u.FullName); err != nil {
+	if err := scanUser(r.db.QueryRowContext(ctx, query, args...), &u); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrRowNotFound
 		}
@@ -65,56 +158,233 @@ func (r *userRepository) GetByUsername(username string) (*model.User, error) {
 	return &u, nil
 }
 
-func (r *userRepository) GetByID(id int64) (*model.User, error) {
-	var u model.User
-	if err := r.db.QueryRowContext(context.Background(), `SELECT id, username, email, full_name FROM users WHERE id = $1`, id).
-		Scan(&u.ID, &u.Username, &u.Email, &u.FullName); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, ErrRowNotFound
+func (r *userRepository) Create(ctx context.Context, user *model.User) (*model.User, error) {
+	if err := r.inTx(ctx, func(tx *sql.Tx) error {
+		return insertUser(ctx, tx, user)
+	}); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *userRepository) CreateMany(ctx context.Context, users []*model.User, atomic bool) ([]error, error) {
+	errs := make([]error, len(users))
+	if !atomic {
+		for i, u := range users {
+			_, errs[i] = r.Create(ctx, u)
 		}
+		return errs, nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
 		return nil, err
 	}
-	return &u, nil
+	defer func() { _ = tx.Rollback() }()
+
+	failed := false
+	for i, u := range users {
+		// A savepoint per user keeps the transaction usable after a unique
+		// constraint violation, so every conflicting user gets reported.
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT create_user`); err != nil {
+			return nil, err
+		}
+		err := insertUser(ctx, tx, u)
+		if err == nil {
+			if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT create_user`); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var ue *UniqueConstraintError
+		if !errors.As(err, &ue) {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT create_user`); err != nil {
+			return nil, err
+		}
+		errs[i] = err
+		failed = true
+	}
+
+	if failed {
+		rolledBack := 0
+		for i := range errs {
+			if errs[i] == nil {
+				errs[i] = ErrRolledBack
+				rolledBack++
+			}
+		}
+		r.logger.InfoContext(ctx, "rolled back atomic user insert", slog.Int("users", len(users)), slog.Int("rolled_back", rolledBack))
+		return errs, tx.Rollback()
+	}
+	return errs, tx.Commit()
 }
 
-func (r *userRepository) Create(user *model.User) (*model.User, error) {
-	if err := r.db.QueryRowContext(context.Background(), `INSERT INTO users (username, email, full_name) VALUES ($1, $2, $3) RETURNING id, username, email, full_name`, user.Username, user.Email, user.FullName).
-		Scan(&user.ID, &user.Username, &user.Email, &user.FullName); err != nil {
+func (r *userRepository) Taken(ctx context.Context, usernames, emails []string) ([]string, []string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT username, email FROM users WHERE (username = ANY($1) OR email = ANY($2)) AND deleted_at IS NULL`, pq.Array(usernames), pq.Array(emails))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	wanted := func(values []string) map[string]bool {
+		m := make(map[string]bool, len(values))
+		for _, v := range values {
+			m[v] = true
+		}
+		return m
+	}
+	wantedUsernames, wantedEmails := wanted(usernames), wanted(emails)
+
+	var takenUsernames, takenEmails []string
+	for rows.Next() {
+		var username, email string
+		if err := rows.Scan(&username, &email); err != nil {
+			return nil, nil, err
+		}
+		if wantedUsernames[username] {
+			takenUsernames = append(takenUsernames, username)
+		}
+		if wantedEmails[email] {
+			takenEmails = append(takenEmails, email)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return takenUsernames, takenEmails, rows.Close()
+}
+
+// insertUser inserts user and audits its creation.
+func insertUser(ctx context.Context, q queryer, user *model.User) error {
+	if err := scanUser(q.QueryRowContext(ctx, `INSERT INTO users (username, email, full_name) VALUES ($1, $2, $3) RETURNING `+userColumns, user.Username, user.Email, user.FullName), user); err != nil {
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
-			return nil, handleUniqueConstraintError(pqErr.Constraint)
+			return handleUniqueConstraintError(pqErr.Constraint)
+		}
+		return err
+	}
+	return writeAudit(ctx, q, model.AuditCreate, nil, user)
+}
+
+func (r *userRepository) Delete(ctx context.Context, id int64, version int64) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		before, err := lockUser(ctx, tx, id, false)
+		if err != nil {
+			return err
+		}
+		if version != 0 && before.Version != version {
+			return ErrVersionConflict
+		}
+
+		var after model.User
+		if err := scanUser(tx.QueryRowContext(ctx, `UPDATE users SET deleted_at = now(), version = version + 1 WHERE id = $1 RETURNING `+userColumns, id), &after); err != nil {
+			return err
+		}
+		return writeAudit(ctx, tx, model.AuditDelete, before, &after)
+	})
+}
+
+func (r *userRepository) Update(ctx context.Context, user *model.User) (*model.User, error) {
+	if err := r.inTx(ctx, func(tx *sql.Tx) error {
+		before, err := lockUser(ctx, tx, user.ID, false)
+		if err != nil {
+			return err
 		}
+		if user.Version != 0 && before.Version != user.Version {
+			return ErrVersionConflict
+		}
+
+		if err := scanUser(tx.QueryRowContext(ctx, `UPDATE users SET username = $1, email = $2, full_name = $3, version = version + 1 WHERE id = $4 RETURNING `+userColumns, user.Username, user.Email, user.FullName, user.ID), user); err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return handleUniqueConstraintError(pqErr.Constraint)
+			}
+			return err
+		}
+		return writeAudit(ctx, tx, model.AuditUpdate, before, user)
+	}); err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (r *userRepository) Delete(id int64) error {
-	var idCheck int64
-	if err := r.db.QueryRowContext(context.Background(), `DELETE FROM users WHERE id = $1 RETURNING id`, id).
-		Scan(&idCheck); err != nil {
-		if err == sql.ErrNoRows {
-			return ErrRowNotFound
-		} else {
+func (r *userRepository) Restore(ctx context.Context, id int64) (*model.User, error) {
+	var u model.User
+	if err := r.inTx(ctx, func(tx *sql.Tx) error {
+		before, err := lockUser(ctx, tx, id, true)
+		if err != nil {
 			return err
 		}
+		if before.DeletedAt == nil {
+			return ErrNotDeleted
+		}
+
+		if err := scanUser(tx.QueryRowContext(ctx, `UPDATE users SET deleted_at = NULL, version = version + 1 WHERE id = $1 RETURNING `+userColumns, id), &u); err != nil {
+			// The username or email may have been reused meanwhile.
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return handleUniqueConstraintError(pqErr.Constraint)
+			}
+			return err
+		}
+		return writeAudit(ctx, tx, model.AuditRestore, before, &u)
+	}); err != nil {
+		return nil, err
 	}
-	if idCheck == 0 {
-		return ErrRowNotFound
+	return &u, nil
+}
+
+// purgeQuery deletes the users and audits each of them in one statement. The
+// before snapshot is built to match the JSON of model.User.
+const purgeQuery = `WITH purged AS (
+	DELETE FROM users WHERE deleted_at IS NOT NULL AND deleted_at < $1 RETURNING *
+)
+INSERT INTO user_audit (user_id, user_uuid, actor, action, before)
+SELECT id, uuid, $2, $3, json_build_object(
+	'id', id, 'uuid', uuid, 'username', username, 'email', email, 'full_name', full_name,
+	'created_at', created_at, 'updated_at', updated_at, 'deleted_at', deleted_at
+) FROM purged`
+
+func (r *userRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, purgeQuery, deletedBefore, auth.Actor(ctx), model.AuditPurge)
+	if err != nil {
+		return 0, err
+	}
+	purged, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	r.logger.InfoContext(ctx, "purged deleted users", slog.Int64("count", purged), slog.Time("deleted_before", deletedBefore))
+	return purged, nil
+}
+
+// inTx runs fn in a transaction that is committed when fn succeeds and rolled
+// back otherwise.
+func (r *userRepository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(tx); err != nil {
+		return err
 	}
-	return nil
+	return tx.Commit()
 }
 
-func (r *userRepository) Update(user *model.User) (*model.User, error) {
-	if err := r.db.QueryRowContext(context.Background(), `UPDATE users SET username = $1, email = $2, full_name = $3 WHERE id = $4 RETURNING id, username, email, full_name`, user.Username, user.Email, user.FullName, user.ID).
-		Scan(&user.ID, &user.Username, &user.Email, &user.FullName); err != nil {
+// lockUser reads the user and locks its row until the end of the transaction,
+// so that it stays a faithful before snapshot of the change that follows.
+func lockUser(ctx context.Context, tx *sql.Tx, id int64, includeDeleted bool) (*model.User, error) {
+	var u model.User
+	if err := scanUser(tx.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1 AND ($2 OR deleted_at IS NULL) FOR UPDATE`, id, includeDeleted), &u); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrRowNotFound
-		} else if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
-			return nil, handleUniqueConstraintError(pqErr.Constraint)
 		}
 		return nil, err
 	}
-	return user, nil
+	return &u, nil
 }
 
 func handleUniqueConstraintError(constraint string) error {
@@ -128,7 +398,12 @@ func handleUniqueConstraintError(constraint string) error {
 	}
 }
 
-var ErrRowNotFound = errors.New("user not found")
+var (
+	ErrRowNotFound     = errors.New("user not found")
+	ErrVersionConflict = errors.New("user version does not match")
+	ErrNotDeleted      = errors.New("user is not deleted")
+	ErrRolledBack      = errors.New("user insert rolled back")
+)
 
 type UniqueConstraintError struct {
 	Field string
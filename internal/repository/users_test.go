@@ -1,15 +1,30 @@
 package repository
 
 import (
+	"bytes"
+	"context"
+	"cruder/internal/auth"
+	"cruder/internal/logging"
 	"cruder/internal/model"
 	"database/sql"
+	"log/slog"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
+const (
+	johnUUID = "123e4567-e89b-12d3-a456-426614174000"
+	janeUUID = "9b2f6c1e-4d3a-4f5b-8e7c-2a1d0f9e8b7c"
+)
+
+var createdAt = time.Date(2025, 9, 23, 8, 43, 49, 0, time.UTC)
+
+var discardLogger = slog.New(slog.DiscardHandler)
+
 func newMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
 	db, mock, _ := sqlmock.New()
 	t.Cleanup(func() { _ = db.Close() })
@@ -20,15 +35,16 @@ func newMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
 func TestGetAll_Success(t *testing.T) {
 	// Given: a mock db with two users returned from query
 	db, mock := newMockDB(t)
-	repo := NewUserRepository(db)
-	rows := sqlmock.NewRows([]string{"id", "username", "email", "full_name"}).
-		AddRow(1, "john_doe", "john@doe.ee", "John Doe").
-		AddRow(2, "jane_doe", "jane@doe.ee", "Jane Doe")
-	mock.ExpectQuery(`SELECT id, username, email, full_name FROM users`).
+	repo := NewUserRepository(db, discardLogger)
+	rows := sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version"}).
+		AddRow(1, johnUUID, "john_doe", "john@doe.ee", "John Doe", createdAt, createdAt, nil, 1).
+		AddRow(2, janeUUID, "jane_doe", "jane@doe.ee", "Jane Doe", createdAt, createdAt, nil, 1)
+	mock.ExpectQuery(`SELECT id, uuid, username, email, full_name, created_at, updated_at, deleted_at, version FROM users WHERE deleted_at IS NULL ORDER BY id ASC LIMIT \$1`).
+		WithArgs(10).
 		WillReturnRows(rows)
 
-	// When: calling GetAll
-	users, err := repo.GetAll()
+	// When: calling GetAll for the first page
+	users, err := repo.GetAll(context.Background(), ListOptions{Limit: 10, Sort: []SortField{{Field: "id"}}})
 
 	// Then: two users should be returned without error
 	assert.NoError(t, err)
@@ -38,18 +54,174 @@ func TestGetAll_Success(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestGetAll_FilterSortAndKeyset(t *testing.T) {
+	// Given: a mock db expecting a filtered, sorted query after a keyset
+	db, mock := newMockDB(t)
+	repo := NewUserRepository(db, discardLogger)
+	rows := sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version"}).
+		AddRow(2, janeUUID, "jane_doe", "jane@doe.ee", "Jane Doe", createdAt, createdAt, nil, 1)
+	mock.ExpectQuery(`SELECT id, uuid, username, email, full_name, created_at, updated_at, deleted_at, version FROM users `+
+		`WHERE deleted_at IS NULL AND username LIKE \$1 AND lower\(split_part\(email, '@', 2\)\) = \$2 `+
+		`AND \(\(username < \$3\) OR \(username = \$3 AND id > \$4\)\) `+
+		`ORDER BY username DESC, id ASC LIMIT \$5`).
+		WithArgs(`j\_%`, "doe.ee", "john_doe", int64(1), 10).
+		WillReturnRows(rows)
+
+	// When: calling GetAll with a prefix filter, domain filter, sort and keyset
+	users, err := repo.GetAll(context.Background(), ListOptions{
+		Limit:  10,
+		Filter: UserFilter{UsernamePrefix: "j_", EmailDomain: "DOE.ee"},
+		Sort:   []SortField{{Field: "username", Desc: true}, {Field: "id"}},
+		After:  []any{"john_doe", int64(1)},
+	})
+
+	// Then: the matching user should be returned without error
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+	assert.Equal(t, "jane_doe", users[0].Username)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAll_TimestampFilters(t *testing.T) {
+	// Given: a mock db expecting users created and updated after given times
+	db, mock := newMockDB(t)
+	repo := NewUserRepository(db, discardLogger)
+	since := createdAt.Add(time.Hour)
+	rows := sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version"}).
+		AddRow(2, janeUUID, "jane_doe", "jane@doe.ee", "Jane Doe", createdAt, since.Add(time.Minute), nil, 2)
+	mock.ExpectQuery(`SELECT id, uuid, username, email, full_name, created_at, updated_at, deleted_at, version FROM users `+
+		`WHERE deleted_at IS NULL AND created_at > \$1 AND updated_at > \$2 AND \(\(updated_at > \$3\) OR \(updated_at = \$3 AND id > \$4\)\) `+
+		`ORDER BY updated_at ASC, id ASC LIMIT \$5`).
+		WithArgs(createdAt, since, since.Format(time.RFC3339Nano), int64(1), 10).
+		WillReturnRows(rows)
+
+	// When: calling GetAll with timestamp filters sorted by updated_at
+	users, err := repo.GetAll(context.Background(), ListOptions{
+		Limit:  10,
+		Filter: UserFilter{CreatedAfter: createdAt, UpdatedAfter: since},
+		Sort:   []SortField{{Field: "updated_at"}, {Field: "id"}},
+		After:  []any{since.Format(time.RFC3339Nano), int64(1)},
+	})
+
+	// Then: the user should be returned with its timestamps
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+	assert.Equal(t, createdAt, users[0].CreatedAt)
+	assert.Equal(t, since.Add(time.Minute), users[0].UpdatedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStream_AllUsers(t *testing.T) {
+	// Given: a mock db with two users and no limit on the query
+	db, mock := newMockDB(t)
+	repo := NewUserRepository(db, discardLogger)
+	rows := sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version"}).
+		AddRow(1, johnUUID, "john_doe", "john@doe.ee", "John Doe", createdAt, createdAt, nil, 1).
+		AddRow(2, janeUUID, "jane_doe", "jane@doe.ee", "Jane Doe", createdAt, createdAt, nil, 1)
+	mock.ExpectQuery(`SELECT id, uuid, username, email, full_name, created_at, updated_at, deleted_at, version FROM users WHERE deleted_at IS NULL ORDER BY id ASC$`).
+		WillReturnRows(rows)
+
+	// When: calling Stream without a limit
+	var usernames []string
+	err := repo.Stream(context.Background(), ListOptions{Sort: []SortField{{Field: "id"}}}, func(u *model.User) error {
+		usernames = append(usernames, u.Username)
+		return nil
+	})
+
+	// Then: every user should be passed to the callback in order
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"john_doe", "jane_doe"}, usernames)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStream_CallbackError(t *testing.T) {
+	// Given: a mock db with two users
+	db, mock := newMockDB(t)
+	repo := NewUserRepository(db, discardLogger)
+	rows := sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version"}).
+		AddRow(1, johnUUID, "john_doe", "john@doe.ee", "John Doe", createdAt, createdAt, nil, 1).
+		AddRow(2, janeUUID, "jane_doe", "jane@doe.ee", "Jane Doe", createdAt, createdAt, nil, 1)
+	mock.ExpectQuery(`SELECT (.+) FROM users`).WillReturnRows(rows)
+
+	// When: the callback fails on the first user
+	calls := 0
+	err := repo.Stream(context.Background(), ListOptions{Sort: []SortField{{Field: "id"}}}, func(u *model.User) error {
+		calls++
+		return sql.ErrConnDone
+	})
+
+	// Then: streaming should stop with the callback error
+	assert.ErrorIs(t, err, sql.ErrConnDone)
+	assert.Equal(t, 1, calls)
+}
+
+func TestSearch_RankedByScore(t *testing.T) {
+	// Given: a mock db returning two scored matches
+	db, mock := newMockDB(t)
+	repo := NewUserRepository(db, discardLogger)
+	rows := sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version", "score"}).
+		AddRow(1, johnUUID, "john_doe", "john@doe.ee", "John Doe", createdAt, createdAt, nil, 1, 1.0).
+		AddRow(2, janeUUID, "jane_doe", "jane@doe.ee", "Jane Doe", createdAt, createdAt, nil, 1, 0.6)
+	mock.ExpectQuery(`SELECT id, uuid, username, email, full_name, created_at, updated_at, deleted_at, version, score FROM \(.+\$1 <% username OR \$1 <% email OR \$1 <% full_name\) \) AS matches ORDER BY score DESC, id ASC LIMIT \$2`).
+		WithArgs("john", 20).
+		WillReturnRows(rows)
+
+	// When: calling Search
+	users, err := repo.Search(context.Background(), "john", 20)
+
+	// Then: the users should be returned with their scores
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+	assert.Equal(t, "john_doe", users[0].Username)
+	assert.Equal(t, 1.0, users[0].Score)
+	assert.Equal(t, 0.6, users[1].Score)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetAll_UnknownSortField(t *testing.T) {
+	// Given: a mock db that should not be queried
+	db, mock := newMockDB(t)
+	repo := NewUserRepository(db, discardLogger)
+
+	// When: calling GetAll with a sort field outside the whitelist
+	users, err := repo.GetAll(context.Background(), ListOptions{Limit: 10, Sort: []SortField{{Field: "password"}}})
+
+	// Then: an error should be returned without running a query
+	assert.Error(t, err)
+	assert.Nil(t, users)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserSortValue(t *testing.T) {
+	// Given: a user
+	created := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	user := model.User{ID: 7, Username: "jdoe", CreatedAt: created}
+
+	// When: reading the cursor value of sortable and unknown fields
+	id, idOK := UserSortValue(user, "id")
+	createdAt, createdOK := UserSortValue(user, "created_at")
+	_, unknownOK := UserSortValue(user, "password")
+
+	// Then: every sortable field should have a value and unknown ones none
+	assert.True(t, idOK)
+	assert.Equal(t, "7", id)
+	assert.True(t, createdOK)
+	assert.Equal(t, created.Format(time.RFC3339Nano), createdAt)
+	assert.False(t, unknownOK)
+}
+
 func TestGetByUsername_Success(t *testing.T) {
 	// Given: a user with username "john_doe" exists
 	db, mock := newMockDB(t)
-	repo := NewUserRepository(db)
-	row := sqlmock.NewRows([]string{"id", "username", "email", "full_name"}).
-		AddRow(1, "john_doe", "john@doe.ee", "John Doe")
-	mock.ExpectQuery(`SELECT id, username, email, full_name FROM users WHERE username = \$1`).
-		WithArgs("john_doe").
+	repo := NewUserRepository(db, discardLogger)
+	row := sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version"}).
+		AddRow(1, johnUUID, "john_doe", "john@doe.ee", "John Doe", createdAt, createdAt, nil, 1)
+	mock.ExpectQuery(`SELECT id, uuid, username, email, full_name, created_at, updated_at, deleted_at, version FROM users WHERE username = \$1 AND \(\$2 OR deleted_at IS NULL\)\s+ORDER BY deleted_at DESC NULLS FIRST LIMIT 1`).
+		WithArgs("john_doe", false).
 		WillReturnRows(row)
 
 	// When: calling GetByUsername with "john_doe"
-	user, err := repo.GetByUsername("john_doe")
+	user, err := repo.GetByUsername(context.Background(), "john_doe", false)
 
 	// Then: the user should be returned with matching ID and username
 	assert.NoError(t, err)
@@ -61,13 +233,13 @@ func TestGetByUsername_Success(t *testing.T) {
 func TestGetByUsername_NotFound(t *testing.T) {
 	// Given: no user exists with username "missing"
 	db, mock := newMockDB(t)
-	repo := NewUserRepository(db)
-	mock.ExpectQuery(`SELECT id, username, email, full_name FROM users WHERE username = \$1`).
-		WithArgs("missing").
+	repo := NewUserRepository(db, discardLogger)
+	mock.ExpectQuery(`SELECT id, uuid, username, email, full_name, created_at, updated_at, deleted_at, version FROM users WHERE username = \$1 AND \(\$2 OR deleted_at IS NULL\)`).
+		WithArgs("missing", false).
 		WillReturnError(sql.ErrNoRows)
 
 	// When: calling GetByUsername with "missing"
-	user, err := repo.GetByUsername("missing")
+	user, err := repo.GetByUsername(context.Background(), "missing", false)
 
 	// Then: ErrUserNotFound should be returned and user should be nil
 	assert.ErrorIs(t, err, ErrRowNotFound)
@@ -75,18 +247,34 @@ func TestGetByUsername_NotFound(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestGetByID_ContextCanceled(t *testing.T) {
+	// Given: a request context that has already been canceled
+	db, mock := newMockDB(t)
+	repo := NewUserRepository(db, discardLogger)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// When: calling GetByID with the canceled context
+	user, err := repo.GetByID(ctx, 1, false)
+
+	// Then: the cancellation should be returned without querying the database
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, user)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestGetByID_Success(t *testing.T) {
 	// Given: a user with ID 1 exists
 	db, mock := newMockDB(t)
-	repo := NewUserRepository(db)
-	row := sqlmock.NewRows([]string{"id", "username", "email", "full_name"}).
-		AddRow(1, "john_doe", "john@doe.ee", "John Doe")
-	mock.ExpectQuery(`SELECT id, username, email, full_name FROM users WHERE id = \$1`).
-		WithArgs(int64(1)).
+	repo := NewUserRepository(db, discardLogger)
+	row := sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version"}).
+		AddRow(1, johnUUID, "john_doe", "john@doe.ee", "John Doe", createdAt, createdAt, nil, 1)
+	mock.ExpectQuery(`SELECT id, uuid, username, email, full_name, created_at, updated_at, deleted_at, version FROM users WHERE id = \$1 AND \(\$2 OR deleted_at IS NULL\)`).
+		WithArgs(int64(1), false).
 		WillReturnRows(row)
 
 	// When: calling GetByID with 1
-	user, err := repo.GetByID(1)
+	user, err := repo.GetByID(context.Background(), 1, false)
 
 	// Then: the user should be returned without error
 	assert.NoError(t, err)
@@ -94,16 +282,53 @@ func TestGetByID_Success(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestGetByUUID_Success(t *testing.T) {
+	// Given: a user with a known UUID exists
+	db, mock := newMockDB(t)
+	repo := NewUserRepository(db, discardLogger)
+	row := sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version"}).
+		AddRow(1, johnUUID, "john_doe", "john@doe.ee", "John Doe", createdAt, createdAt, nil, 1)
+	mock.ExpectQuery(`SELECT id, uuid, username, email, full_name, created_at, updated_at, deleted_at, version FROM users WHERE uuid = \$1 AND \(\$2 OR deleted_at IS NULL\)`).
+		WithArgs(johnUUID, false).
+		WillReturnRows(row)
+
+	// When: calling GetByUUID with the UUID
+	user, err := repo.GetByUUID(context.Background(), johnUUID, false)
+
+	// Then: the user should be returned with both identifiers set
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), user.ID)
+	assert.Equal(t, johnUUID, user.UUID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetByUUID_NotFound(t *testing.T) {
+	// Given: no user exists with the UUID
+	db, mock := newMockDB(t)
+	repo := NewUserRepository(db, discardLogger)
+	mock.ExpectQuery(`SELECT id, uuid, username, email, full_name, created_at, updated_at, deleted_at, version FROM users WHERE uuid = \$1 AND \(\$2 OR deleted_at IS NULL\)`).
+		WithArgs(janeUUID, false).
+		WillReturnError(sql.ErrNoRows)
+
+	// When: calling GetByUUID with the UUID
+	user, err := repo.GetByUUID(context.Background(), janeUUID, false)
+
+	// Then: ErrRowNotFound should be returned and user should be nil
+	assert.ErrorIs(t, err, ErrRowNotFound)
+	assert.Nil(t, user)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestGetByID_NotFound(t *testing.T) {
 	// Given: no user exists with ID 99
 	db, mock := newMockDB(t)
-	repo := NewUserRepository(db)
-	mock.ExpectQuery(`SELECT id, username, email, full_name FROM users WHERE id = \$1`).
-		WithArgs(int64(99)).
+	repo := NewUserRepository(db, discardLogger)
+	mock.ExpectQuery(`SELECT id, uuid, username, email, full_name, created_at, updated_at, deleted_at, version FROM users WHERE id = \$1 AND \(\$2 OR deleted_at IS NULL\)`).
+		WithArgs(int64(99), false).
 		WillReturnError(sql.ErrNoRows)
 
 	// When: calling GetByID with 99
-	user, err := repo.GetByID(99)
+	user, err := repo.GetByID(context.Background(), 99, false)
 
 	// Then: ErrUserNotFound should be returned and user should be nil
 	assert.ErrorIs(t, err, ErrRowNotFound)
@@ -111,38 +336,180 @@ func TestGetByID_NotFound(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+// expectAudit expects the audit record of a change to the given user.
+func expectAudit(mock sqlmock.Sqlmock, userID int64, action model.AuditAction, actor string) {
+	mock.ExpectExec(`INSERT INTO user_audit \(user_id, user_uuid, actor, action, before, after\) VALUES \(\$1, \$2, \$3, \$4, \$5, \$6\)`).
+		WithArgs(userID, sqlmock.AnyArg(), actor, string(action), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+// expectLock expects the before snapshot of a change to be read and locked.
+func expectLock(mock sqlmock.Sqlmock, id int64, includeDeleted bool) *sqlmock.ExpectedQuery {
+	return mock.ExpectQuery(`SELECT id, uuid, username, email, full_name, created_at, updated_at, deleted_at, version FROM users WHERE id = \$1 AND \(\$2 OR deleted_at IS NULL\) FOR UPDATE`).
+		WithArgs(id, includeDeleted)
+}
+
 func TestCreateUser_Success(t *testing.T) {
-	// Given: a new user to be inserted successfully
+	// Given: a new user to be inserted successfully by the onboarding actor
 	db, mock := newMockDB(t)
-	repo := NewUserRepository(db)
+	repo := NewUserRepository(db, discardLogger)
 	newUser := &model.User{Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe"}
-	row := sqlmock.NewRows([]string{"id", "username", "email", "full_name"}).
-		AddRow(1, newUser.Username, newUser.Email, newUser.FullName)
+	row := sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version"}).
+		AddRow(1, johnUUID, newUser.Username, newUser.Email, newUser.FullName, createdAt, createdAt, nil, 1)
+	mock.ExpectBegin()
 	mock.ExpectQuery(`INSERT INTO users`).
 		WithArgs(newUser.Username, newUser.Email, newUser.FullName).
 		WillReturnRows(row)
+	mock.ExpectExec(`INSERT INTO user_audit`).
+		WithArgs(int64(1), johnUUID, "onboarding", string(model.AuditCreate), sql.NullString{},
+			sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	// When: calling Create with newUser
-	created, err := repo.Create(newUser)
+	ctx := auth.WithActor(context.Background(), "onboarding")
+	created, err := repo.Create(ctx, newUser)
 
-	// Then: user should be returned with ID set
+	// Then: user should be returned with ID set and its creation audited
 	assert.NoError(t, err)
 	assert.Equal(t, int64(1), created.ID)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestCreateMany_Atomic_Success(t *testing.T) {
+	// Given: two new users inserted in a transaction
+	db, mock := newMockDB(t)
+	repo := NewUserRepository(db, discardLogger)
+	users := []*model.User{
+		{Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe"},
+		{Username: "jane_doe", Email: "jane@doe.ee", FullName: "Jane Doe"},
+	}
+	mock.ExpectBegin()
+	for i, u := range users {
+		mock.ExpectExec(`SAVEPOINT create_user`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`INSERT INTO users`).
+			WithArgs(u.Username, u.Email, u.FullName).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version"}).
+				AddRow(i+1, johnUUID, u.Username, u.Email, u.FullName, createdAt, createdAt, nil, 1))
+		expectAudit(mock, int64(i+1), model.AuditCreate, auth.UnknownActor)
+		mock.ExpectExec(`RELEASE SAVEPOINT create_user`).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	mock.ExpectCommit()
+
+	// When: calling CreateMany in atomic mode
+	errs, err := repo.CreateMany(context.Background(), users, true)
+
+	// Then: both users should be created and the transaction committed
+	assert.NoError(t, err)
+	assert.Equal(t, []error{nil, nil}, errs)
+	assert.Equal(t, int64(2), users[1].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateMany_Atomic_RollsBackOnConflict(t *testing.T) {
+	// Given: the second of two users has a duplicate email
+	db, mock := newMockDB(t)
+	repo := NewUserRepository(db, discardLogger)
+	users := []*model.User{
+		{Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe"},
+		{Username: "jane_doe", Email: "john@doe.ee", FullName: "Jane Doe"},
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec(`SAVEPOINT create_user`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`INSERT INTO users`).
+		WithArgs("john_doe", "john@doe.ee", "John Doe").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version"}).
+			AddRow(1, johnUUID, "john_doe", "john@doe.ee", "John Doe", createdAt, createdAt, nil, 1))
+	expectAudit(mock, 1, model.AuditCreate, auth.UnknownActor)
+	mock.ExpectExec(`RELEASE SAVEPOINT create_user`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`SAVEPOINT create_user`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`INSERT INTO users`).
+		WithArgs("jane_doe", "john@doe.ee", "Jane Doe").
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "users_email_key"})
+	mock.ExpectExec(`ROLLBACK TO SAVEPOINT create_user`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	// When: calling CreateMany in atomic mode
+	errs, err := repo.CreateMany(context.Background(), users, true)
+
+	// Then: the conflict should be reported and the first insert rolled back
+	assert.NoError(t, err)
+	assert.ErrorIs(t, errs[0], ErrRolledBack)
+	var ce *UniqueConstraintError
+	if assert.ErrorAs(t, errs[1], &ce) {
+		assert.Equal(t, "email", ce.Field)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateMany_BestEffort(t *testing.T) {
+	// Given: the first of two users has a duplicate username
+	db, mock := newMockDB(t)
+	repo := NewUserRepository(db, discardLogger)
+	users := []*model.User{
+		{Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe"},
+		{Username: "jane_doe", Email: "jane@doe.ee", FullName: "Jane Doe"},
+	}
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO users`).
+		WithArgs("john_doe", "john@doe.ee", "John Doe").
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "users_username_key"})
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO users`).
+		WithArgs("jane_doe", "jane@doe.ee", "Jane Doe").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version"}).
+			AddRow(2, janeUUID, "jane_doe", "jane@doe.ee", "Jane Doe", createdAt, createdAt, nil, 1))
+	expectAudit(mock, 2, model.AuditCreate, auth.UnknownActor)
+	mock.ExpectCommit()
+
+	// When: calling CreateMany without atomic mode
+	errs, err := repo.CreateMany(context.Background(), users, false)
+
+	// Then: the second user should be created despite the first failing
+	assert.NoError(t, err)
+	var ce *UniqueConstraintError
+	assert.ErrorAs(t, errs[0], &ce)
+	assert.NoError(t, errs[1])
+	assert.Equal(t, janeUUID, users[1].UUID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTaken(t *testing.T) {
+	// Given: active john_doe exists and jane uses an email that is asked for
+	db, mock := newMockDB(t)
+	repo := NewUserRepository(db, discardLogger)
+	rows := sqlmock.NewRows([]string{"username", "email"}).
+		AddRow("john_doe", "john@doe.ee").
+		AddRow("jane", "jane@doe.ee")
+	mock.ExpectQuery(`SELECT username, email FROM users WHERE \(username = ANY\(\$1\) OR email = ANY\(\$2\)\) AND deleted_at IS NULL`).
+		WithArgs(pq.Array([]string{"john_doe", "jane_doe"}), pq.Array([]string{"john.doe@doe.ee", "jane@doe.ee"})).
+		WillReturnRows(rows)
+
+	// When: calling Taken with two usernames and emails
+	usernames, emails, err := repo.Taken(context.Background(), []string{"john_doe", "jane_doe"}, []string{"john.doe@doe.ee", "jane@doe.ee"})
+
+	// Then: only the values asked for should be reported as taken
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"john_doe"}, usernames)
+	assert.Equal(t, []string{"jane@doe.ee"}, emails)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestCreateUser_DuplicateUsername(t *testing.T) {
 	// Given: inserting a user with duplicate username
 	db, mock := newMockDB(t)
-	repo := NewUserRepository(db)
+	repo := NewUserRepository(db, discardLogger)
 	newUser := &model.User{Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe"}
 	pqErr := &pq.Error{Code: "23505", Constraint: "users_username_key"}
+	mock.ExpectBegin()
 	mock.ExpectQuery(`INSERT INTO users`).
 		WithArgs(newUser.Username, newUser.Email, newUser.FullName).
 		WillReturnError(pqErr)
+	mock.ExpectRollback()
 
 	// When: calling Create with duplicate username
-	created, err := repo.Create(newUser)
+	created, err := repo.Create(context.Background(), newUser)
 
 	// Then: UniqueConstraintError with field username should be returned
 	var ce *UniqueConstraintError
@@ -156,15 +523,17 @@ func TestCreateUser_DuplicateUsername(t *testing.T) {
 func TestCreateUser_DuplicateEmail(t *testing.T) {
 	// Given: inserting a user with duplicate email
 	db, mock := newMockDB(t)
-	repo := NewUserRepository(db)
+	repo := NewUserRepository(db, discardLogger)
 	newUser := &model.User{Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe"}
 	pqErr := &pq.Error{Code: "23505", Constraint: "users_email_key"}
+	mock.ExpectBegin()
 	mock.ExpectQuery(`INSERT INTO users`).
 		WithArgs(newUser.Username, newUser.Email, newUser.FullName).
 		WillReturnError(pqErr)
+	mock.ExpectRollback()
 
 	// When: calling Create with duplicate email
-	created, err := repo.Create(newUser)
+	created, err := repo.Create(context.Background(), newUser)
 
 	// Then: UniqueConstraintError with field email should be returned
 	var ce *UniqueConstraintError
@@ -178,15 +547,17 @@ func TestCreateUser_DuplicateEmail(t *testing.T) {
 func TestCreateUser_DuplicateOther(t *testing.T) {
 	// Given: inserting a user fails due to other duplicate constraint
 	db, mock := newMockDB(t)
-	repo := NewUserRepository(db)
+	repo := NewUserRepository(db, discardLogger)
 	newUser := &model.User{Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe"}
 	pqErr := &pq.Error{Code: "23505", Constraint: "other_key"}
+	mock.ExpectBegin()
 	mock.ExpectQuery(`INSERT INTO users`).
 		WithArgs(newUser.Username, newUser.Email, newUser.FullName).
 		WillReturnError(pqErr)
+	mock.ExpectRollback()
 
 	// When: calling Create with some other duplicate
-	created, err := repo.Create(newUser)
+	created, err := repo.Create(context.Background(), newUser)
 
 	// Then: UniqueConstraintError should be returned
 	var ce *UniqueConstraintError
@@ -200,14 +571,19 @@ func TestCreateUser_DuplicateOther(t *testing.T) {
 func TestDeleteUser_Success(t *testing.T) {
 	// Given: a user with ID 1 exists and will be deleted
 	db, mock := newMockDB(t)
-	repo := NewUserRepository(db)
-	rows := sqlmock.NewRows([]string{"id"}).AddRow(1)
-	mock.ExpectQuery(`DELETE FROM users WHERE id = \$1 RETURNING id`).
+	repo := NewUserRepository(db, discardLogger)
+	mock.ExpectBegin()
+	expectLock(mock, 1, false).WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version"}).
+		AddRow(1, johnUUID, "john_doe", "john@doe.ee", "John Doe", createdAt, createdAt, nil, 1))
+	mock.ExpectQuery(`UPDATE users SET deleted_at = now\(\), version = version \+ 1 WHERE id = \$1 RETURNING`).
 		WithArgs(int64(1)).
-		WillReturnRows(rows)
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version"}).
+			AddRow(1, johnUUID, "john_doe", "john@doe.ee", "John Doe", createdAt, createdAt, createdAt, 2))
+	expectAudit(mock, 1, model.AuditDelete, auth.UnknownActor)
+	mock.ExpectCommit()
 
-	// When: calling Delete with ID 1
-	err := repo.Delete(1)
+	// When: calling Delete with ID 1 without a version
+	err := repo.Delete(context.Background(), 1, 0)
 
 	// Then: no error should be returned
 	assert.NoError(t, err)
@@ -217,53 +593,183 @@ func TestDeleteUser_Success(t *testing.T) {
 func TestDeleteUser_NotFound(t *testing.T) {
 	// Given: no user exists with ID 99
 	db, mock := newMockDB(t)
-	repo := NewUserRepository(db)
-	mock.ExpectQuery(`DELETE FROM users WHERE id = \$1 RETURNING id`).
-		WithArgs(int64(99)).
-		WillReturnError(sql.ErrNoRows)
+	repo := NewUserRepository(db, discardLogger)
+	mock.ExpectBegin()
+	expectLock(mock, 99, false).WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
 
 	// When: calling Delete with ID 99
-	err := repo.Delete(99)
+	err := repo.Delete(context.Background(), 99, 0)
 
 	// Then: ErrUserNotFound should be returned
 	assert.ErrorIs(t, err, ErrRowNotFound)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestDeleteUser_VersionConflict(t *testing.T) {
+	// Given: user 1 exists but its version is no longer 2
+	db, mock := newMockDB(t)
+	repo := NewUserRepository(db, discardLogger)
+	mock.ExpectBegin()
+	expectLock(mock, 1, false).WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version"}).
+		AddRow(1, johnUUID, "john_doe", "john@doe.ee", "John Doe", createdAt, createdAt, nil, 3))
+	mock.ExpectRollback()
+
+	// When: calling Delete with the stale version
+	err := repo.Delete(context.Background(), 1, 2)
+
+	// Then: ErrVersionConflict should be returned
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestUpdateUser_Success(t *testing.T) {
 	// Given: an existing user is updated successfully
 	db, mock := newMockDB(t)
-	repo := NewUserRepository(db)
+	repo := NewUserRepository(db, discardLogger)
 	user := &model.User{ID: 1, Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe"}
-	row := sqlmock.NewRows([]string{"id", "username", "email", "full_name"}).
-		AddRow(user.ID, user.Username, user.Email, user.FullName)
-	mock.ExpectQuery(`UPDATE users SET username = \$1, email = \$2, full_name = \$3 WHERE id = \$4 RETURNING id, username, email, full_name`).
+	mock.ExpectBegin()
+	expectLock(mock, 1, false).WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version"}).
+		AddRow(1, johnUUID, "john", "john@doe.ee", "John", createdAt, createdAt, nil, 1))
+	mock.ExpectQuery(`UPDATE users SET username = \$1, email = \$2, full_name = \$3, version = version \+ 1 WHERE id = \$4 RETURNING id, uuid, username, email, full_name, created_at, updated_at, deleted_at, version`).
 		WithArgs(user.Username, user.Email, user.FullName, user.ID).
-		WillReturnRows(row)
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version"}).
+			AddRow(user.ID, johnUUID, user.Username, user.Email, user.FullName, createdAt, createdAt, nil, 2))
+	mock.ExpectExec(`INSERT INTO user_audit`).
+		WithArgs(int64(1), johnUUID, auth.UnknownActor, string(model.AuditUpdate), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 
 	// When: calling Update with existing user
-	updated, err := repo.Update(user)
+	updated, err := repo.Update(context.Background(), user)
 
 	// Then: updated user should be returned without error
 	assert.NoError(t, err)
 	assert.Equal(t, int64(1), updated.ID)
+	assert.Equal(t, int64(2), updated.Version)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestUpdateUser_NotFound(t *testing.T) {
 	// Given: no user exists with ID 99
 	db, mock := newMockDB(t)
-	repo := NewUserRepository(db)
+	repo := NewUserRepository(db, discardLogger)
 	user := &model.User{ID: 99, Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe"}
-	mock.ExpectQuery(`UPDATE users SET username = \$1, email = \$2, full_name = \$3 WHERE id = \$4 RETURNING id, username, email, full_name`).
-		WithArgs(user.Username, user.Email, user.FullName, user.ID).
-		WillReturnError(sql.ErrNoRows)
+	mock.ExpectBegin()
+	expectLock(mock, 99, false).WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
 
 	// When: calling Update with non-existing user
-	updated, err := repo.Update(user)
+	updated, err := repo.Update(context.Background(), user)
 
 	// Then: ErrUserNotFound should be returned
 	assert.ErrorIs(t, err, ErrRowNotFound)
 	assert.Nil(t, updated)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestUpdateUser_VersionConflict(t *testing.T) {
+	// Given: user 1 exists but its version is no longer 3
+	db, mock := newMockDB(t)
+	repo := NewUserRepository(db, discardLogger)
+	user := &model.User{ID: 1, Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe", Version: 3}
+	mock.ExpectBegin()
+	expectLock(mock, 1, false).WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version"}).
+		AddRow(1, johnUUID, "john_doe", "john@doe.ee", "John Doe", createdAt, createdAt, nil, 4))
+	mock.ExpectRollback()
+
+	// When: calling Update with the stale version
+	updated, err := repo.Update(context.Background(), user)
+
+	// Then: ErrVersionConflict should be returned
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	assert.Nil(t, updated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreUser_Success(t *testing.T) {
+	// Given: user 1 is soft deleted
+	db, mock := newMockDB(t)
+	repo := NewUserRepository(db, discardLogger)
+	mock.ExpectBegin()
+	expectLock(mock, 1, true).WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version"}).
+		AddRow(1, johnUUID, "john_doe", "john@doe.ee", "John Doe", createdAt, createdAt, createdAt, 2))
+	mock.ExpectQuery(`UPDATE users SET deleted_at = NULL, version = version \+ 1 WHERE id = \$1 RETURNING`).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version"}).
+			AddRow(1, johnUUID, "john_doe", "john@doe.ee", "John Doe", createdAt, createdAt, nil, 3))
+	expectAudit(mock, 1, model.AuditRestore, auth.UnknownActor)
+	mock.ExpectCommit()
+
+	// When: calling Restore with ID 1
+	user, err := repo.Restore(context.Background(), 1)
+
+	// Then: the restored user should be returned without a deletion time
+	assert.NoError(t, err)
+	assert.Nil(t, user.DeletedAt)
+	assert.Equal(t, int64(3), user.Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreUser_UsernameReused(t *testing.T) {
+	// Given: user 1 is soft deleted and its username now belongs to another user
+	db, mock := newMockDB(t)
+	repo := NewUserRepository(db, discardLogger)
+	mock.ExpectBegin()
+	expectLock(mock, 1, true).WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version"}).
+		AddRow(1, johnUUID, "john_doe", "john@doe.ee", "John Doe", createdAt, createdAt, createdAt, 2))
+	mock.ExpectQuery(`UPDATE users SET deleted_at = NULL`).
+		WithArgs(int64(1)).
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "users_username_key"})
+	mock.ExpectRollback()
+
+	// When: calling Restore with ID 1
+	user, err := repo.Restore(context.Background(), 1)
+
+	// Then: the username conflict should be reported
+	var ce *UniqueConstraintError
+	assert.ErrorAs(t, err, &ce)
+	assert.Equal(t, "username", ce.Field)
+	assert.Nil(t, user)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreUser_NotDeleted(t *testing.T) {
+	// Given: user 1 exists and is not deleted
+	db, mock := newMockDB(t)
+	repo := NewUserRepository(db, discardLogger)
+	mock.ExpectBegin()
+	expectLock(mock, 1, true).WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "username", "email", "full_name", "created_at", "updated_at", "deleted_at", "version"}).
+		AddRow(1, johnUUID, "john_doe", "john@doe.ee", "John Doe", createdAt, createdAt, nil, 2))
+	mock.ExpectRollback()
+
+	// When: calling Restore with ID 1
+	user, err := repo.Restore(context.Background(), 1)
+
+	// Then: ErrNotDeleted should be returned
+	assert.ErrorIs(t, err, ErrNotDeleted)
+	assert.Nil(t, user)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeUsers_Success(t *testing.T) {
+	// Given: two users were deleted before the cutoff, and a logger that records request IDs
+	db, mock := newMockDB(t)
+	var logs bytes.Buffer
+	repo := NewUserRepository(db, slog.New(logging.NewContextHandler(slog.NewTextHandler(&logs, nil))))
+	cutoff := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectExec(`WITH purged AS \(\s*DELETE FROM users WHERE deleted_at IS NOT NULL AND deleted_at < \$1 RETURNING \*\s*\)\s*INSERT INTO user_audit`).
+		WithArgs(cutoff, "retention", string(model.AuditPurge)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	// When: calling Purge with the cutoff
+	ctx := logging.WithRequestID(auth.WithActor(context.Background(), "retention"), "req-1")
+	purged, err := repo.Purge(ctx, cutoff)
+
+	// Then: the number of purged users should be returned and logged with the request ID
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), purged)
+	assert.Contains(t, logs.String(), "count=2")
+	assert.Contains(t, logs.String(), "request_id=req-1")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
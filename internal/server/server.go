@@ -0,0 +1,56 @@
+// Package server runs the HTTP server and shuts it down gracefully.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+type Options struct {
+	// DrainDelay is how long readiness fails before the server stops
+	// accepting connections, giving load balancers time to stop sending new
+	// requests.
+	DrainDelay time.Duration
+	// ShutdownTimeout is how long in-flight requests get to finish once the
+	// server stops accepting connections. Connections still open after that
+	// are closed.
+	ShutdownTimeout time.Duration
+}
+
+// Serve serves srv on ln until ctx is done, then shuts it down: drain is
+// called so that readiness starts failing, and after opts.DrainDelay the
+// server stops accepting connections and waits for in-flight requests. Serve
+// returns once every connection is closed, so that the resources the handlers
+// use can be released.
+func Serve(ctx context.Context, srv *http.Server, ln net.Listener, drain func(), opts Options, logger *slog.Logger) error {
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.Serve(ln) }()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("server stopped: %w", err)
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down, draining connections",
+		slog.Duration("drain_delay", opts.DrainDelay),
+		slog.Duration("shutdown_timeout", opts.ShutdownTimeout))
+	drain()
+	time.Sleep(opts.DrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), opts.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		_ = srv.Close()
+		return fmt.Errorf("failed to drain connections: %w", err)
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("server stopped: %w", err)
+	}
+	return nil
+}
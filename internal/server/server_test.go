@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// helper to serve a handler that blocks until release is closed
+func startServer(t *testing.T, opts Options) (url string, started, release chan struct{}, drained *atomic.Bool, cancel context.CancelFunc, done chan error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	started, release = make(chan struct{}, 1), make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			started <- struct{}{}
+			select {
+			case <-release:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		_, _ = io.WriteString(w, "ok")
+	})}
+	drained = new(atomic.Bool)
+	ctx, cancel := context.WithCancel(context.Background())
+	done = make(chan error, 1)
+	go func() { done <- Serve(ctx, srv, ln, func() { drained.Store(true) }, opts, discardLogger) }()
+	t.Cleanup(cancel)
+	return "http://" + ln.Addr().String(), started, release, drained, cancel, done
+}
+
+func get(url string) chan int {
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			status <- 0
+			return
+		}
+		_ = resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	return status
+}
+
+func TestServe_DrainsInFlightRequests(t *testing.T) {
+	// Given: a request in flight
+	url, started, release, drained, cancel, done := startServer(t, Options{DrainDelay: 100 * time.Millisecond, ShutdownTimeout: time.Second})
+	slow := get(url + "/slow")
+	<-started
+
+	// When: the server is told to shut down
+	cancel()
+
+	// Then: it should drain first while still accepting requests
+	assert.Eventually(t, drained.Load, time.Second, time.Millisecond)
+	assert.Equal(t, http.StatusOK, <-get(url+"/fast"))
+
+	// And: the request in flight should be allowed to finish
+	close(release)
+	assert.Equal(t, http.StatusOK, <-slow)
+	assert.NoError(t, <-done)
+	assert.Equal(t, 0, <-get(url+"/fast"), "expected connections to be refused")
+}
+
+func TestServe_ShutdownTimeout(t *testing.T) {
+	// Given: a request that outlives the shutdown timeout
+	url, started, release, _, cancel, done := startServer(t, Options{ShutdownTimeout: 20 * time.Millisecond})
+	defer close(release)
+	slow := get(url + "/slow")
+	<-started
+
+	// When: the server is told to shut down
+	cancel()
+
+	// Then: the request should be cut off and the shutdown reported as failed
+	select {
+	case err := <-done:
+		assert.ErrorContains(t, err, "failed to drain connections")
+	case <-time.After(time.Second):
+		t.Fatal("expected Serve to give up on the request")
+	}
+	assert.Equal(t, 0, <-slow)
+}
+
+func TestServe_ListenerFails(t *testing.T) {
+	// Given: a listener that is already closed
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	_ = ln.Close()
+
+	// When: the server is started
+	err = Serve(context.Background(), &http.Server{}, ln, func() {}, Options{}, discardLogger)
+
+	// Then: it should fail right away
+	assert.ErrorContains(t, err, "server stopped")
+}
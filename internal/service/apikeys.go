@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/auth"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultRotationOverlap is how long a rotated key keeps working unless
+	// told otherwise.
+	DefaultRotationOverlap = 24 * time.Hour
+	MaxRotationOverlap     = 30 * 24 * time.Hour
+	MaxAPIKeyNameLength    = 100
+	// lastUsedInterval is how stale the recorded last use of a key may get,
+	// so that a busy key is not written on every request.
+	lastUsedInterval = time.Minute
+)
+
+type APIKeyService interface {
+	// Create makes a new key and returns it along with its plaintext secret,
+	// which is not stored and cannot be retrieved again.
+	Create(ctx context.Context, req model.CreateAPIKeyRequest) (*model.CreatedAPIKey, error)
+	List(ctx context.Context) ([]model.APIKey, error)
+	Revoke(ctx context.Context, id int64) (*model.APIKey, error)
+	// Rotate replaces the key with a new one that has the same name, scopes
+	// and expiry. The old key keeps working for overlap.
+	Rotate(ctx context.Context, id int64, overlap time.Duration) (*model.CreatedAPIKey, error)
+	// Lookup implements auth.KeyStore for the stored keys.
+	Lookup(ctx context.Context, secret string) (*auth.Key, error)
+}
+
+type apiKeyService struct {
+	repo repository.APIKeyRepository
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepository) APIKeyService {
+	return &apiKeyService{repo: repo}
+}
+
+func (s *apiKeyService) Create(ctx context.Context, req model.CreateAPIKeyRequest) (*model.CreatedAPIKey, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > MaxAPIKeyNameLength {
+		return nil, ErrInvalidAPIKeyName
+	}
+	if name == auth.LegacyKeyName {
+		return nil, ErrReservedAPIKeyName
+	}
+	if len(req.Scopes) == 0 {
+		return nil, ErrInvalidScopes
+	}
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, s := range req.Scopes {
+		if _, err := auth.ParseScope(s); err != nil {
+			return nil, ErrInvalidScopes
+		}
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidExpiry
+	}
+
+	created, err := newAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	created.Name, created.Scopes, created.ExpiresAt = name, scopes, req.ExpiresAt
+	if err := s.repo.Create(ctx, &created.APIKey); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (s *apiKeyService) List(ctx context.Context) ([]model.APIKey, error) {
+	keys, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		keys = []model.APIKey{}
+	}
+	return keys, nil
+}
+
+func (s *apiKeyService) Revoke(ctx context.Context, id int64) (*model.APIKey, error) {
+	key, err := s.repo.Revoke(ctx, id)
+	if errors.Is(err, repository.ErrKeyNotFound) {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+func (s *apiKeyService) Rotate(ctx context.Context, id int64, overlap time.Duration) (*model.CreatedAPIKey, error) {
+	if overlap < 0 || overlap > MaxRotationOverlap {
+		return nil, ErrInvalidOverlap
+	}
+
+	created, err := newAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	err = s.repo.Rotate(ctx, id, &created.APIKey, time.Now().Add(overlap))
+	switch {
+	case errors.Is(err, repository.ErrKeyNotFound):
+		return nil, ErrAPIKeyNotFound
+	case errors.Is(err, repository.ErrKeyNotActive):
+		return nil, ErrAPIKeyNotActive
+	case err != nil:
+		return nil, err
+	}
+	return created, nil
+}
+
+func (s *apiKeyService) Lookup(ctx context.Context, secret string) (*auth.Key, error) {
+	prefix, ok := auth.KeyPrefix(secret)
+	if !ok {
+		return nil, auth.ErrUnknownKey
+	}
+	key, err := s.repo.GetByPrefix(ctx, prefix)
+	if errors.Is(err, repository.ErrKeyNotFound) {
+		return nil, auth.ErrUnknownKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !auth.KeyMatches(key.Salt, key.Hash, secret) ||
+		key.RevokedAt != nil ||
+		(key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now())) {
+		return nil, auth.ErrUnknownKey
+	}
+
+	if key.LastUsedAt == nil || time.Since(*key.LastUsedAt) >= lastUsedInterval {
+		if err := s.repo.Touch(ctx, key.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	scopes := make([]auth.Scope, len(key.Scopes))
+	for i, s := range key.Scopes {
+		scopes[i] = auth.Scope(s)
+	}
+	return &auth.Key{ID: key.Prefix, Name: key.Name, Scopes: scopes}, nil
+}
+
+// newAPIKey generates a key along with the salted hash that is stored for it.
+func newAPIKey() (*model.CreatedAPIKey, error) {
+	secret, prefix, err := auth.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	salt, err := auth.NewSalt()
+	if err != nil {
+		return nil, err
+	}
+	return &model.CreatedAPIKey{
+		APIKey: model.APIKey{Prefix: prefix, Salt: salt, Hash: auth.HashKey(salt, secret)},
+		Key:    secret,
+	}, nil
+}
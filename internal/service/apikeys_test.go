@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/auth"
+	mock_repository "cruder/internal/mocks/repository"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+// Given: A valid API key request with a repeated scope
+func TestCreateAPIKey_Success(t *testing.T) {
+	// Setup: Create mock repository that captures the stored key
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockAPIKeyRepository(ctrl)
+	keyService := NewAPIKeyService(mockRepo)
+
+	var stored *model.APIKey
+	mockRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, k *model.APIKey) error {
+		stored = k
+		k.ID = 1
+		return nil
+	}).Times(1)
+
+	// When: Calling create
+	created, err := keyService.Create(context.Background(), model.CreateAPIKeyRequest{
+		Name:   " crm ",
+		Scopes: []string{"users:read", "users:read"},
+	})
+
+	// Then: Only a hash of the returned plaintext key should be stored
+	assert.NoError(t, err, "expected no error")
+	assert.Equal(t, "crm", stored.Name, "expected trimmed name")
+	assert.Equal(t, []string{"users:read"}, stored.Scopes, "expected deduplicated scopes")
+	assert.NotContains(t, string(stored.Hash), created.Key, "expected key not to be stored")
+	assert.True(t, auth.KeyMatches(stored.Salt, stored.Hash, created.Key), "expected hash to match key")
+	prefix, _ := auth.KeyPrefix(created.Key)
+	assert.Equal(t, stored.Prefix, prefix, "expected stored prefix to match key")
+}
+
+// Given: Invalid API key requests
+func TestCreateAPIKey_Invalid_Fails(t *testing.T) {
+	// Setup: Create mock repository that must not be called
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockAPIKeyRepository(ctrl)
+	keyService := NewAPIKeyService(mockRepo)
+
+	mockRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Times(0)
+	past := time.Now().Add(-time.Hour)
+
+	// When: Calling create without a name, with the reserved name, with an unknown scope and with a past expiry
+	_, nameErr := keyService.Create(context.Background(), model.CreateAPIKeyRequest{Name: " ", Scopes: []string{"users:read"}})
+	_, reservedErr := keyService.Create(context.Background(), model.CreateAPIKeyRequest{Name: "default", Scopes: []string{"users:read"}})
+	_, scopeErr := keyService.Create(context.Background(), model.CreateAPIKeyRequest{Name: "crm", Scopes: []string{"users:admin"}})
+	_, expiryErr := keyService.Create(context.Background(), model.CreateAPIKeyRequest{Name: "crm", Scopes: []string{"users:read"}, ExpiresAt: &past})
+
+	// Then: The matching validation errors should be returned
+	assert.ErrorIs(t, nameErr, ErrInvalidAPIKeyName)
+	assert.ErrorIs(t, reservedErr, ErrReservedAPIKeyName)
+	assert.ErrorIs(t, scopeErr, ErrInvalidScopes)
+	assert.ErrorIs(t, expiryErr, ErrInvalidExpiry)
+}
+
+// Given: Stored keys in different states
+func TestLookupAPIKey(t *testing.T) {
+	secret, prefix, _ := auth.GenerateKey()
+	salt, _ := auth.NewSalt()
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Minute)
+	recently := time.Now().Add(-10 * time.Second)
+
+	tests := map[string]struct {
+		key       model.APIKey
+		secret    string
+		wantErr   error
+		wantTouch bool
+	}{
+		"active":           {model.APIKey{ExpiresAt: &future}, secret, nil, true},
+		"used a while ago": {model.APIKey{LastUsedAt: &past}, secret, nil, true},
+		"used recently":    {model.APIKey{LastUsedAt: &recently}, secret, nil, false},
+		"wrong secret":     {model.APIKey{}, prefix + "_wrong", auth.ErrUnknownKey, false},
+		"revoked":          {model.APIKey{RevokedAt: &past}, secret, auth.ErrUnknownKey, false},
+		"overlap passed":   {model.APIKey{ExpiresAt: &past}, secret, auth.ErrUnknownKey, false},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// Setup: Create mock repository holding the key
+			ctrl := gomock.NewController(t)
+			mockRepo := mock_repository.NewMockAPIKeyRepository(ctrl)
+			keyService := NewAPIKeyService(mockRepo)
+
+			tt.key.ID, tt.key.Prefix, tt.key.Name, tt.key.Scopes = 1, prefix, "crm", []string{"users:read"}
+			tt.key.Salt, tt.key.Hash = salt, auth.HashKey(salt, secret)
+			mockRepo.EXPECT().GetByPrefix(gomock.Any(), prefix).Return(&tt.key, nil).Times(1)
+			if tt.wantTouch {
+				mockRepo.EXPECT().Touch(gomock.Any(), int64(1)).Return(nil).Times(1)
+			}
+
+			// When: Looking up the secret
+			key, err := keyService.Lookup(context.Background(), tt.secret)
+
+			// Then: Only the active key with the right secret should be found, its use recorded at most once a minute
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				assert.Equal(t, &auth.Key{ID: prefix, Name: "crm", Scopes: []auth.Scope{auth.ScopeUsersRead}}, key)
+			}
+		})
+	}
+}
+
+// Given: A key that has already been rotated
+func TestRotateAPIKey_NotActive_Fails(t *testing.T) {
+	// Setup: Create mock repository refusing the rotation
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockAPIKeyRepository(ctrl)
+	keyService := NewAPIKeyService(mockRepo)
+
+	mockRepo.EXPECT().Rotate(gomock.Any(), int64(1), gomock.Any(), gomock.Any()).
+		Return(repository.ErrKeyNotActive).Times(1)
+
+	// When: Rotating the key and rotating with a too long overlap
+	_, err := keyService.Rotate(context.Background(), 1, time.Hour)
+	_, overlapErr := keyService.Rotate(context.Background(), 1, MaxRotationOverlap+time.Hour)
+
+	// Then: The matching errors should be returned
+	assert.ErrorIs(t, err, ErrAPIKeyNotActive)
+	assert.ErrorIs(t, overlapErr, ErrInvalidOverlap)
+}
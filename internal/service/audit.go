@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+)
+
+// auditCursorSort marks cursors issued for audit pages, which always run
+// from the newest entry to the oldest.
+const auditCursorSort = "-id"
+
+var auditActions = map[model.AuditAction]bool{
+	model.AuditCreate:  true,
+	model.AuditUpdate:  true,
+	model.AuditDelete:  true,
+	model.AuditRestore: true,
+	model.AuditPurge:   true,
+}
+
+type AuditService interface {
+	// List returns a page of audit entries, newest first.
+	List(ctx context.Context, params model.AuditListParams) (*model.AuditPage, error)
+}
+
+type auditService struct {
+	repo repository.AuditRepository
+}
+
+func NewAuditService(repo repository.AuditRepository) AuditService {
+	return &auditService{repo: repo}
+}
+
+func (s *auditService) List(ctx context.Context, params model.AuditListParams) (*model.AuditPage, error) {
+	limit := params.Limit
+	if limit == 0 {
+		limit = DefaultPageLimit
+	}
+	if limit < 1 || limit > MaxPageLimit {
+		return nil, ErrInvalidLimit
+	}
+	if params.Action != "" && !auditActions[params.Action] {
+		return nil, ErrInvalidAuditAction
+	}
+
+	opts := repository.AuditListOptions{
+		// Fetch one extra entry to find out whether another page follows.
+		Limit:    limit + 1,
+		UserID:   params.UserID,
+		UserUUID: params.UserUUID,
+		Actor:    params.Actor,
+		Action:   params.Action,
+		Since:    params.Since,
+		Until:    params.Until,
+	}
+
+	cursor, err := decodeCursor(params.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if cursor != nil {
+		if cursor.Sort != auditCursorSort || cursor.ID < 1 {
+			return nil, ErrInvalidCursor
+		}
+		opts.BeforeID = cursor.ID
+	}
+
+	entries, err := s.repo.List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.AuditPage{Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		page.NextCursor = encodeCursor(pageCursor{Sort: auditCursorSort, ID: page.Entries[limit-1].ID})
+	}
+	if page.Entries == nil {
+		page.Entries = []model.UserAudit{}
+	}
+
+	return page, nil
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	mock_repository "cruder/internal/mocks/repository"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+// Given: More audit entries than fit on a page
+func TestAuditList_Pagination(t *testing.T) {
+	// Setup: Create mock repository returning limit+1 entries and then the rest
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockAuditRepository(ctrl)
+	auditService := NewAuditService(mockRepo)
+
+	first := repository.AuditListOptions{Limit: 3, UserID: 1, Action: model.AuditUpdate}
+	mockRepo.EXPECT().List(gomock.Any(), first).
+		Return([]model.UserAudit{{ID: 9}, {ID: 7}, {ID: 4}}, nil).Times(1)
+	second := first
+	second.BeforeID = 7
+	mockRepo.EXPECT().List(gomock.Any(), second).
+		Return([]model.UserAudit{{ID: 4}}, nil).Times(1)
+
+	// When: Calling list for the first page and then following its cursor
+	params := model.AuditListParams{Limit: 2, UserID: 1, Action: model.AuditUpdate}
+	page, err := auditService.List(context.Background(), params)
+	assert.NoError(t, err, "expected no error")
+	params.Cursor = page.NextCursor
+	next, err := auditService.List(context.Background(), params)
+
+	// Then: The second page should continue below the last entry of the first
+	assert.NoError(t, err, "expected no error")
+	assert.Len(t, page.Entries, 2, "expected page to be trimmed to limit")
+	assert.Equal(t, int64(4), next.Entries[0].ID, "expected next page to hold the oldest entry")
+	assert.Empty(t, next.NextCursor, "expected no cursor after last page")
+}
+
+// Given: Invalid audit list parameters
+func TestAuditList_InvalidParams_Fail(t *testing.T) {
+	// Setup: Create mock repository that must not be called
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockAuditRepository(ctrl)
+	auditService := NewAuditService(mockRepo)
+
+	mockRepo.EXPECT().List(gomock.Any(), gomock.Any()).Times(0)
+	userCursor := encodeCursor(pageCursor{Sort: "id", ID: 3})
+
+	// When: Calling list with an unknown action and with a user list cursor
+	_, actionErr := auditService.List(context.Background(), model.AuditListParams{Action: "drop"})
+	_, cursorErr := auditService.List(context.Background(), model.AuditListParams{Cursor: userCursor})
+
+	// Then: The matching validation errors should be returned
+	assert.ErrorIs(t, actionErr, ErrInvalidAuditAction, "expected invalid audit action error")
+	assert.ErrorIs(t, cursorErr, ErrInvalidCursor, "expected invalid cursor error")
+}
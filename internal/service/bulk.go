@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"errors"
+)
+
+// MaxBulkSize is the maximum number of users a single bulk request may hold.
+const MaxBulkSize = 1000
+
+// BulkResult is the outcome of creating one user of a bulk request: either
+// the created user or the reason it was not created.
+type BulkResult struct {
+	User *model.User
+	Err  error
+}
+
+func (s *userService) CreateBulk(ctx context.Context, users []model.User, atomic bool) ([]BulkResult, error) {
+	if len(users) == 0 || len(users) > MaxBulkSize {
+		return nil, ErrInvalidBulkSize
+	}
+
+	results := make([]BulkResult, len(users))
+	var (
+		valid   []*model.User
+		indexes []int
+	)
+	for i := range users {
+		if err := ValidateUser(users[i]); err != nil {
+			results[i].Err = err
+			continue
+		}
+		valid = append(valid, &users[i])
+		indexes = append(indexes, i)
+	}
+
+	// In atomic mode a single invalid user fails the whole batch, so there
+	// is no point in touching the database.
+	if atomic && len(valid) < len(users) {
+		for _, i := range indexes {
+			results[i].Err = ErrBulkAborted
+		}
+		return results, nil
+	}
+	if err := s.createMany(ctx, results, valid, indexes, atomic); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// createMany creates users and records the outcome of users[j] in
+// results[indexes[j]].
+func (s *userService) createMany(ctx context.Context, results []BulkResult, users []*model.User, indexes []int, atomic bool) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	errs, err := s.repo.CreateMany(ctx, users, atomic)
+	if err != nil {
+		return err
+	}
+	for j, i := range indexes {
+		var ce *repository.UniqueConstraintError
+		switch {
+		case errs[j] == nil:
+			results[i].User = users[j]
+		case errors.Is(errs[j], repository.ErrRolledBack):
+			results[i].Err = ErrBulkAborted
+		case errors.As(errs[j], &ce):
+			results[i].Err = handleUniqueConstraintError(ce)
+		default:
+			results[i].Err = errs[j]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// pageCursor is the position of the last user on a page. It is handed to
+// clients as an opaque base64url string.
+type pageCursor struct {
+	// Sort is the normalized sort the cursor was issued for.
+	Sort string `json:"s"`
+	// Keys holds the values of the non-id sort fields, in sort order.
+	Keys []string `json:"k,omitempty"`
+	ID   int64    `json:"id"`
+}
+
+func newCursor(last model.User, sort []repository.SortField) (pageCursor, error) {
+	c := pageCursor{Sort: formatSort(sort), ID: last.ID}
+	for _, f := range sort {
+		if f.Field == "id" {
+			continue
+		}
+		value, ok := repository.UserSortValue(last, f.Field)
+		if !ok {
+			return pageCursor{}, fmt.Errorf("no cursor value for sort field %q", f.Field)
+		}
+		c.Keys = append(c.Keys, value)
+	}
+	return c, nil
+}
+
+// keyset turns the cursor into the repository's After values for sort.
+func (c pageCursor) keyset(sort []repository.SortField) ([]any, error) {
+	if c.Sort != formatSort(sort) {
+		return nil, ErrInvalidCursor
+	}
+
+	after := make([]any, 0, len(sort))
+	keys := c.Keys
+	for _, f := range sort {
+		if f.Field == "id" {
+			after = append(after, c.ID)
+			continue
+		}
+		if len(keys) == 0 {
+			return nil, ErrInvalidCursor
+		}
+		after = append(after, keys[0])
+		keys = keys[1:]
+	}
+	if len(keys) > 0 {
+		return nil, ErrInvalidCursor
+	}
+	return after, nil
+}
+
+func encodeCursor(c pageCursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (*pageCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c pageCursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// parseSort turns "username,-id" into sort fields. Unknown or repeated fields
+// are rejected, and id is appended as a tiebreaker when missing.
+func parseSort(s string) ([]repository.SortField, error) {
+	var sort []repository.SortField
+	seen := map[string]bool{}
+
+	if s != "" {
+		for _, part := range strings.Split(s, ",") {
+			field := repository.SortField{Field: strings.TrimSpace(part)}
+			if strings.HasPrefix(field.Field, "-") {
+				field.Field, field.Desc = field.Field[1:], true
+			}
+			if !repository.IsSortableUserField(field.Field) || seen[field.Field] {
+				return nil, ErrInvalidSort
+			}
+			seen[field.Field] = true
+			sort = append(sort, field)
+		}
+	}
+
+	if !seen["id"] {
+		sort = append(sort, repository.SortField{Field: "id"})
+	}
+	return sort, nil
+}
+
+func formatSort(sort []repository.SortField) string {
+	parts := make([]string, len(sort))
+	for i, f := range sort {
+		parts[i] = f.Field
+		if f.Desc {
+			parts[i] = "-" + f.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
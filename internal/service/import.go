@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+)
+
+// MaxImportSize is the maximum number of users a single import may hold.
+const MaxImportSize = 10000
+
+func (s *userService) Import(ctx context.Context, users []model.User, dryRun bool) ([]BulkResult, error) {
+	if len(users) == 0 || len(users) > MaxImportSize {
+		return nil, ErrInvalidImportSize
+	}
+
+	results := make([]BulkResult, len(users))
+	var usernames, emails []string
+	for i := range users {
+		if err := ValidateUser(users[i]); err != nil {
+			results[i].Err = err
+			continue
+		}
+		usernames = append(usernames, users[i].Username)
+		emails = append(emails, users[i].Email)
+	}
+
+	var takenUsernames, takenEmails []string
+	if len(usernames) > 0 {
+		var err error
+		if takenUsernames, takenEmails, err = s.repo.Taken(ctx, usernames, emails); err != nil {
+			return nil, err
+		}
+	}
+	usernameTaken := make(map[string]bool, len(users))
+	for _, u := range takenUsernames {
+		usernameTaken[u] = true
+	}
+	emailTaken := make(map[string]bool, len(users))
+	for _, e := range takenEmails {
+		emailTaken[e] = true
+	}
+
+	// Users that pass are marked as taken too, so that only the first of
+	// several rows with the same username or email gets imported.
+	var (
+		valid   []*model.User
+		indexes []int
+	)
+	for i := range users {
+		switch {
+		case results[i].Err != nil:
+			continue
+		case usernameTaken[users[i].Username]:
+			results[i].Err = ErrUsernameAlreadyExists
+		case emailTaken[users[i].Email]:
+			results[i].Err = ErrEmailAlreadyExists
+		default:
+			usernameTaken[users[i].Username] = true
+			emailTaken[users[i].Email] = true
+			valid = append(valid, &users[i])
+			indexes = append(indexes, i)
+		}
+	}
+
+	if dryRun {
+		for j, i := range indexes {
+			results[i].User = valid[j]
+		}
+		return results, nil
+	}
+
+	if err := s.createMany(ctx, results, valid, indexes, true); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
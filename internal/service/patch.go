@@ -0,0 +1,58 @@
+package service
+
+import (
+	"cruder/internal/model"
+	"encoding/json"
+	"errors"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+// PatchFormat is the kind of document a partial update is described with.
+type PatchFormat int
+
+const (
+	// MergePatch is an RFC 7396 JSON Merge Patch (application/merge-patch+json).
+	MergePatch PatchFormat = iota
+	// JSONPatch is an RFC 6902 JSON Patch (application/json-patch+json).
+	JSONPatch
+)
+
+// applyPatch applies patch to the JSON representation of user and returns the
+// resulting user. The identifiers of the user cannot be changed by a patch.
+func applyPatch(user model.User, format PatchFormat, patch []byte) (*model.User, error) {
+	original, err := json.Marshal(user)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc []byte
+	switch format {
+	case MergePatch:
+		doc, err = jsonpatch.MergePatch(original, patch)
+	case JSONPatch:
+		var ops jsonpatch.Patch
+		if ops, err = jsonpatch.DecodePatch(patch); err == nil {
+			doc, err = ops.Apply(original)
+		}
+	default:
+		return nil, ErrInvalidPatch
+	}
+	if errors.Is(err, jsonpatch.ErrTestFailed) {
+		return nil, ErrPatchTestFailed
+	}
+	if err != nil {
+		return nil, ErrInvalidPatch
+	}
+
+	var patched model.User
+	if err := json.Unmarshal(doc, &patched); err != nil {
+		return nil, ErrInvalidPatch
+	}
+	if patched.ID != user.ID || patched.UUID != user.UUID {
+		return nil, ErrImmutableIdentifier
+	}
+	patched.Version = user.Version
+
+	return &patched, nil
+}
@@ -1,13 +1,24 @@
 package service
 
-import "cruder/internal/repository"
+import (
+	"cruder/internal/repository"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
 
 type Service struct {
-	Users UserService
+	Users   UserService
+	Audit   AuditService
+	APIKeys APIKeyService
 }
 
-func NewService(repos *repository.Repository) *Service {
+// NewService creates the services, tracing the calls to the user service with
+// tp. Errors that expected reports are not marked as failures on the spans.
+func NewService(repos *repository.Repository, deletedUserRetention time.Duration, tp trace.TracerProvider, expected func(error) bool) *Service {
 	return &Service{
-		Users: NewUserService(repos.Users),
+		Users:   TraceUserService(NewUserService(repos.Users, deletedUserRetention), tp, expected),
+		Audit:   NewAuditService(repos.Audit),
+		APIKeys: NewAPIKeyService(repos.APIKeys),
 	}
 }
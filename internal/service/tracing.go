@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tracedUserService struct {
+	tracer   trace.Tracer
+	next     UserService
+	expected func(error) bool
+}
+
+// TraceUserService starts a span named after the method around every call to
+// svc. Spans are marked as failed unless expected reports the error as an
+// outcome of the request, such as a user that does not exist.
+func TraceUserService(svc UserService, tp trace.TracerProvider, expected func(error) bool) UserService {
+	return &tracedUserService{tracer: tp.Tracer("cruder/internal/service"), next: svc, expected: expected}
+}
+
+func (s *tracedUserService) start(ctx context.Context, method string) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "UserService."+method)
+}
+
+func (s *tracedUserService) end(span trace.Span, err *error) {
+	if *err != nil && s.expected(*err) {
+		span.End()
+		return
+	}
+	tracing.End(span, err)
+}
+
+func (s *tracedUserService) GetAll(ctx context.Context, params model.UserListParams) (_ *model.UserPage, err error) {
+	ctx, span := s.start(ctx, "GetAll")
+	defer s.end(span, &err)
+	return s.next.GetAll(ctx, params)
+}
+
+func (s *tracedUserService) Export(ctx context.Context, params model.UserListParams, fn func(*model.User) error) (err error) {
+	ctx, span := s.start(ctx, "Export")
+	defer s.end(span, &err)
+	return s.next.Export(ctx, params, fn)
+}
+
+func (s *tracedUserService) Search(ctx context.Context, params model.UserSearchParams) (_ *model.UserSearchResult, err error) {
+	ctx, span := s.start(ctx, "Search")
+	defer s.end(span, &err)
+	return s.next.Search(ctx, params)
+}
+
+func (s *tracedUserService) GetByUsername(ctx context.Context, username string, includeDeleted bool) (_ *model.User, err error) {
+	ctx, span := s.start(ctx, "GetByUsername")
+	defer s.end(span, &err)
+	return s.next.GetByUsername(ctx, username, includeDeleted)
+}
+
+func (s *tracedUserService) GetByID(ctx context.Context, id int64, includeDeleted bool) (_ *model.User, err error) {
+	ctx, span := s.start(ctx, "GetByID")
+	defer s.end(span, &err)
+	return s.next.GetByID(ctx, id, includeDeleted)
+}
+
+func (s *tracedUserService) GetByUUID(ctx context.Context, uuid string, includeDeleted bool) (_ *model.User, err error) {
+	ctx, span := s.start(ctx, "GetByUUID")
+	defer s.end(span, &err)
+	return s.next.GetByUUID(ctx, uuid, includeDeleted)
+}
+
+func (s *tracedUserService) Create(ctx context.Context, user *model.User) (_ *model.User, err error) {
+	ctx, span := s.start(ctx, "Create")
+	defer s.end(span, &err)
+	return s.next.Create(ctx, user)
+}
+
+func (s *tracedUserService) CreateBulk(ctx context.Context, users []model.User, atomic bool) (_ []BulkResult, err error) {
+	ctx, span := s.start(ctx, "CreateBulk")
+	defer s.end(span, &err)
+	return s.next.CreateBulk(ctx, users, atomic)
+}
+
+func (s *tracedUserService) Import(ctx context.Context, users []model.User, dryRun bool) (_ []BulkResult, err error) {
+	ctx, span := s.start(ctx, "Import")
+	defer s.end(span, &err)
+	return s.next.Import(ctx, users, dryRun)
+}
+
+func (s *tracedUserService) Delete(ctx context.Context, id int64, version int64) (err error) {
+	ctx, span := s.start(ctx, "Delete")
+	defer s.end(span, &err)
+	return s.next.Delete(ctx, id, version)
+}
+
+func (s *tracedUserService) Update(ctx context.Context, user *model.User) (_ *model.User, err error) {
+	ctx, span := s.start(ctx, "Update")
+	defer s.end(span, &err)
+	return s.next.Update(ctx, user)
+}
+
+func (s *tracedUserService) Patch(ctx context.Context, id int64, version int64, format PatchFormat, patch []byte) (_ *model.User, err error) {
+	ctx, span := s.start(ctx, "Patch")
+	defer s.end(span, &err)
+	return s.next.Patch(ctx, id, version, format, patch)
+}
+
+func (s *tracedUserService) Restore(ctx context.Context, id int64) (_ *model.User, err error) {
+	ctx, span := s.start(ctx, "Restore")
+	defer s.end(span, &err)
+	return s.next.Restore(ctx, id)
+}
+
+func (s *tracedUserService) Purge(ctx context.Context) (_ int64, err error) {
+	ctx, span := s.start(ctx, "Purge")
+	defer s.end(span, &err)
+	return s.next.Purge(ctx)
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"errors"
+	mock_repository "cruder/internal/mocks/repository"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/mock/gomock"
+)
+
+// Given: A traced user service whose second lookup finds no user and third fails
+func TestTraceUserService_Spans(t *testing.T) {
+	// Setup: Create mock repository that checks the span is passed on
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	expected := func(err error) bool { return errors.Is(err, ErrUserNotFound) }
+	userService := TraceUserService(NewUserService(mockRepo, 0), tp, expected)
+
+	var repoSpans []trace.SpanContext
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(1), false).
+		DoAndReturn(func(ctx context.Context, _ int64, _ bool) (*model.User, error) {
+			repoSpans = append(repoSpans, trace.SpanContextFromContext(ctx))
+			return &model.User{ID: 1}, nil
+		}).Times(1)
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(2), false).
+		Return(nil, repository.ErrRowNotFound).Times(1)
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(3), false).
+		Return(nil, errors.New("connection reset")).Times(1)
+
+	// When: Getting both users
+	_, okErr := userService.GetByID(context.Background(), 1, false)
+	_, notFoundErr := userService.GetByID(context.Background(), 2, false)
+	_, failedErr := userService.GetByID(context.Background(), 3, false)
+
+	// Then: Each call should get a span, only the failed one marked as an error
+	assert.NoError(t, okErr, "expected no error")
+	assert.ErrorIs(t, notFoundErr, ErrUserNotFound)
+	assert.Error(t, failedErr)
+	spans := recorder.Ended()
+	assert.Len(t, spans, 3)
+	assert.Equal(t, "UserService.GetByID", spans[0].Name())
+	assert.Equal(t, spans[0].SpanContext().SpanID(), repoSpans[0].SpanID(), "expected repository to run in the span")
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Unset, spans[1].Status().Code, "expected a missing user not to fail the span")
+	assert.Equal(t, codes.Error, spans[2].Status().Code)
+	assert.Contains(t, spans[2].Status().Description, "connection reset")
+}
@@ -1,35 +1,159 @@
 package service
 
 import (
+	"context"
+	"cruder/internal/auth"
 	"cruder/internal/model"
 	"cruder/internal/repository"
 	"errors"
+	"fmt"
 	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 type UserService interface {
-	GetAll() ([]model.User, error)
-	GetByUsername(username string) (*model.User, error)
-	GetByID(id int64) (*model.User, error)
-	Create(user *model.User) (*model.User, error)
-	Delete(id int64) error
-	Update(user *model.User) (*model.User, error)
+	GetAll(ctx context.Context, params model.UserListParams) (*model.UserPage, error)
+	// Export calls fn for every user matching the filters and sort of params,
+	// ignoring its limit and cursor.
+	Export(ctx context.Context, params model.UserListParams, fn func(*model.User) error) error
+	Search(ctx context.Context, params model.UserSearchParams) (*model.UserSearchResult, error)
+	GetByUsername(ctx context.Context, username string, includeDeleted bool) (*model.User, error)
+	GetByID(ctx context.Context, id int64, includeDeleted bool) (*model.User, error)
+	GetByUUID(ctx context.Context, uuid string, includeDeleted bool) (*model.User, error)
+	Create(ctx context.Context, user *model.User) (*model.User, error)
+	// CreateBulk creates the users and reports the outcome for each of them,
+	// in order. With atomic set either every user is created or none is.
+	CreateBulk(ctx context.Context, users []model.User, atomic bool) ([]BulkResult, error)
+	// Import validates the users and checks they are unique, then creates
+	// the ones that pass in a single transaction. A dry run only reports the
+	// users that would be created.
+	Import(ctx context.Context, users []model.User, dryRun bool) ([]BulkResult, error)
+	// Delete, Update and Patch fail with ErrPreconditionFailed when the
+	// expected version is stale. A zero version skips the check; for Update
+	// the expected version is user.Version.
+	Delete(ctx context.Context, id int64, version int64) error
+	Update(ctx context.Context, user *model.User) (*model.User, error)
+	Patch(ctx context.Context, id int64, version int64, format PatchFormat, patch []byte) (*model.User, error)
+	Restore(ctx context.Context, id int64) (*model.User, error)
+	// Purge permanently removes users that have been soft deleted for longer
+	// than the retention period.
+	Purge(ctx context.Context) (int64, error)
 }
 
 type userService struct {
-	repo repository.UserRepository
+	repo      repository.UserRepository
+	retention time.Duration
 }
 
-func NewUserService(repo repository.UserRepository) UserService {
-	return &userService{repo: repo}
+// NewUserService creates the user service. Soft deleted users are kept for
+// retention before Purge removes them.
+func NewUserService(repo repository.UserRepository, retention time.Duration) UserService {
+	return &userService{repo: repo, retention: retention}
 }
 
-func (s *userService) GetAll() ([]model.User, error) {
-	return s.repo.GetAll()
+func (s *userService) GetAll(ctx context.Context, params model.UserListParams) (*model.UserPage, error) {
+	limit := params.Limit
+	if limit == 0 {
+		limit = DefaultPageLimit
+	}
+	if limit < 1 || limit > MaxPageLimit {
+		return nil, ErrInvalidLimit
+	}
+
+	sort, err := parseSort(params.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := repository.ListOptions{
+		// Fetch one extra row to find out whether another page follows.
+		Limit:  limit + 1,
+		Sort:   sort,
+		Filter: userFilter(params),
+	}
+
+	cursor, err := decodeCursor(params.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	if cursor != nil {
+		if opts.After, err = cursor.keyset(sort); err != nil {
+			return nil, err
+		}
+	}
+
+	users, err := s.repo.GetAll(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &model.UserPage{Users: users}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		next, err := newCursor(page.Users[limit-1], sort)
+		if err != nil {
+			return nil, err
+		}
+		page.NextCursor = encodeCursor(next)
+	}
+	if page.Users == nil {
+		page.Users = []model.User{}
+	}
+
+	return page, nil
+}
+
+func (s *userService) Export(ctx context.Context, params model.UserListParams, fn func(*model.User) error) error {
+	sort, err := parseSort(params.Sort)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.Stream(ctx, repository.ListOptions{Sort: sort, Filter: userFilter(params)}, fn)
+}
+
+func userFilter(params model.UserListParams) repository.UserFilter {
+	return repository.UserFilter{
+		Username:          params.Username,
+		UsernamePrefix:    params.UsernamePrefix,
+		EmailDomain:       params.EmailDomain,
+		EmailDomainPrefix: params.EmailDomainPrefix,
+		FullName:          params.FullName,
+		FullNamePrefix:    params.FullNamePrefix,
+		CreatedAfter:      params.CreatedAfter,
+		UpdatedAfter:      params.UpdatedAfter,
+		IncludeDeleted:    params.IncludeDeleted,
+	}
+}
+
+func (s *userService) Search(ctx context.Context, params model.UserSearchParams) (*model.UserSearchResult, error) {
+	query := strings.TrimSpace(params.Query)
+	if query == "" || utf8.RuneCountInString(query) > MaxSearchQueryLength {
+		return nil, ErrInvalidSearchQuery
+	}
+
+	limit := params.Limit
+	if limit == 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit < 1 || limit > MaxPageLimit {
+		return nil, ErrInvalidLimit
+	}
+
+	users, err := s.repo.Search(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	if users == nil {
+		users = []model.ScoredUser{}
+	}
+	return &model.UserSearchResult{Users: users}, nil
 }
 
-func (s *userService) GetByUsername(username string) (*model.User, error) {
-	user, err := s.repo.GetByUsername(username)
+func (s *userService) GetByUsername(ctx context.Context, username string, includeDeleted bool) (*model.User, error) {
+	user, err := s.repo.GetByUsername(ctx, username, includeDeleted)
 
 	if err != nil {
 		if errors.Is(err, repository.ErrRowNotFound) {
@@ -41,8 +165,8 @@ func (s *userService) GetByUsername(username string) (*model.User, error) {
 	return user, nil
 }
 
-func (s *userService) GetByID(id int64) (*model.User, error) {
-	user, err := s.repo.GetByID(id)
+func (s *userService) GetByID(ctx context.Context, id int64, includeDeleted bool) (*model.User, error) {
+	user, err := s.repo.GetByID(ctx, id, includeDeleted)
 
 	if err != nil {
 		if errors.Is(err, repository.ErrRowNotFound) {
@@ -54,11 +178,24 @@ func (s *userService) GetByID(id int64) (*model.User, error) {
 	return user, nil
 }
 
-func (s *userService) Create(user *model.User) (*model.User, error) {
+func (s *userService) GetByUUID(ctx context.Context, uuid string, includeDeleted bool) (*model.User, error) {
+	user, err := s.repo.GetByUUID(ctx, uuid, includeDeleted)
+
+	if err != nil {
+		if errors.Is(err, repository.ErrRowNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *userService) Create(ctx context.Context, user *model.User) (*model.User, error) {
 	if err := ValidateUser(*user); err != nil {
 		return nil, err
 	}
-	user, err := s.repo.Create(user)
+	user, err := s.repo.Create(ctx, user)
 
 	if ce, ok := err.(*repository.UniqueConstraintError); ok {
 		return nil, handleUniqueConstraintError(ce)
@@ -78,25 +215,31 @@ func handleUniqueConstraintError(err *repository.UniqueConstraintError) error {
 	}
 }
 
-func (s *userService) Delete(id int64) error {
-	err := s.repo.Delete(id)
+func (s *userService) Delete(ctx context.Context, id int64, version int64) error {
+	err := s.repo.Delete(ctx, id, version)
 
 	if errors.Is(err, repository.ErrRowNotFound) {
 		return ErrUserNotFound
 	}
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return ErrPreconditionFailed
+	}
 	return err
 }
 
-func (s *userService) Update(user *model.User) (*model.User, error) {
+func (s *userService) Update(ctx context.Context, user *model.User) (*model.User, error) {
 	if err := ValidateUser(*user); err != nil {
 		return nil, err
 	}
 
-	user, err := s.repo.Update(user)
+	user, err := s.repo.Update(ctx, user)
 
 	if errors.Is(err, repository.ErrRowNotFound) {
 		return nil, ErrUserNotFound
 	}
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return nil, ErrPreconditionFailed
+	}
 
 	if ce, ok := err.(*repository.UniqueConstraintError); ok {
 		return nil, handleUniqueConstraintError(ce)
@@ -104,6 +247,49 @@ func (s *userService) Update(user *model.User) (*model.User, error) {
 	return user, err
 }
 
+// Patch applies a partial update to the user with the given id. Only the
+// merged result is validated, so the patch only needs the changed fields.
+// The write is conditional on the version the patch was applied to, so a
+// concurrent update in between is reported instead of overwritten.
+func (s *userService) Patch(ctx context.Context, id int64, version int64, format PatchFormat, patch []byte) (*model.User, error) {
+	current, err := s.GetByID(ctx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	if version != 0 && current.Version != version {
+		return nil, ErrPreconditionFailed
+	}
+
+	patched, err := applyPatch(*current, format, patch)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Update(ctx, patched)
+}
+
+func (s *userService) Restore(ctx context.Context, id int64) (*model.User, error) {
+	user, err := s.repo.Restore(ctx, id)
+
+	if ce, ok := err.(*repository.UniqueConstraintError); ok {
+		return nil, handleUniqueConstraintError(ce)
+	}
+	if errors.Is(err, repository.ErrRowNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if errors.Is(err, repository.ErrNotDeleted) {
+		return nil, ErrUserNotDeleted
+	}
+	return user, err
+}
+
+func (s *userService) Purge(ctx context.Context) (int64, error) {
+	if s.retention <= 0 {
+		return 0, ErrPurgeDisabled
+	}
+	return s.repo.Purge(ctx, time.Now().Add(-s.retention))
+}
+
 func ValidateUser(user model.User) error {
 	if !emailRegex.MatchString(user.Email) {
 		return ErrInvalidEmail
@@ -117,6 +303,14 @@ func ValidateUser(user model.User) error {
 	return nil
 }
 
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 500
+
+	DefaultSearchLimit   = 20
+	MaxSearchQueryLength = 100
+)
+
 var emailRegex = regexp.MustCompile(`^[\w-\.]+@([\w-]+\.)+[\w-]{2,4}$`)
 var usernameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]{2,49}$`)
 var fullNameRegex = regexp.MustCompile(`^[A-Za-z][A-Za-z' -]{0,98}[A-Za-z]$`)
@@ -129,4 +323,25 @@ var (
 	ErrInvalidEmail          = errors.New("invalid email format")
 	ErrInvalidUsername       = errors.New("invalid username format (3-50 chars, lowercase letters, numbers, underscores, starts with letter)")
 	ErrInvalidFullName       = errors.New("invalid full name format (2-100 chars, letters, spaces, apostrophes, hyphens, starts/ends with letter)")
+	ErrInvalidLimit          = fmt.Errorf("invalid limit (must be between 1 and %d)", MaxPageLimit)
+	ErrInvalidCursor         = errors.New("invalid cursor")
+	ErrInvalidPatch          = errors.New("invalid patch document")
+	ErrPatchTestFailed       = errors.New("patch test operation failed")
+	ErrImmutableIdentifier   = errors.New("id and uuid cannot be changed")
+	ErrPreconditionFailed    = errors.New("user has been modified, version does not match If-Match")
+	ErrUserNotDeleted        = errors.New("user is not deleted")
+	ErrPurgeDisabled         = errors.New("purging deleted users is disabled, no retention period is configured")
+	ErrInvalidBulkSize       = fmt.Errorf("invalid bulk size (must hold between 1 and %d users)", MaxBulkSize)
+	ErrInvalidImportSize     = fmt.Errorf("invalid import size (must hold between 1 and %d users)", MaxImportSize)
+	ErrBulkAborted           = errors.New("user not created, another user in the atomic batch failed")
+	ErrInvalidSearchQuery    = fmt.Errorf("invalid search query (must be between 1 and %d characters)", MaxSearchQueryLength)
+	ErrInvalidAuditAction    = errors.New("invalid audit action (create, update, delete, restore or purge)")
+	ErrAPIKeyNotFound        = errors.New("API key not found")
+	ErrAPIKeyNotActive       = errors.New("API key is revoked, expired or already rotated")
+	ErrInvalidAPIKeyName     = fmt.Errorf("invalid API key name (must be between 1 and %d characters)", MaxAPIKeyNameLength)
+	ErrReservedAPIKeyName    = fmt.Errorf("API key name %q is reserved for the X_API_KEY key", auth.LegacyKeyName)
+	ErrInvalidScopes         = errors.New("invalid scopes (at least one of users:read, users:write, audit:read, keys:admin)")
+	ErrInvalidExpiry         = errors.New("invalid expiry (must be in the future)")
+	ErrInvalidOverlap        = errors.New("invalid overlap (must be a duration between 0s and 720h)")
+	ErrInvalidSort           = errors.New("invalid sort (comma separated list of id, username, email, full_name, created_at, updated_at, prefix with - for descending)")
 )
@@ -1,9 +1,13 @@
 package service
 
 import (
+	"context"
 	mock_repository "cruder/internal/mocks/repository"
 	"cruder/internal/model"
+	"cruder/internal/repository"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
@@ -14,7 +18,7 @@ func TestCreateUser_Success(t *testing.T) {
 	// Setup: Create mock repository, service and user
 	ctrl := gomock.NewController(t)
 	mockRepo := mock_repository.NewMockUserRepository(ctrl)
-	userService := NewUserService(mockRepo)
+	userService := NewUserService(mockRepo, 0)
 
 	newUser := &model.User{
 		Username: "john_doe",
@@ -22,10 +26,10 @@ func TestCreateUser_Success(t *testing.T) {
 		FullName: "John Doe",
 	}
 
-	mockRepo.EXPECT().Create(newUser).Return(newUser, nil).Times(1)
+	mockRepo.EXPECT().Create(gomock.Any(), newUser).Return(newUser, nil).Times(1)
 
 	// When: Calling create from user service
-	createdUser, err := userService.Create(newUser)
+	createdUser, err := userService.Create(context.Background(), newUser)
 
 	// Then: The result should be the created user and no error
 	assert.NoError(t, err, "expected no error")
@@ -37,7 +41,7 @@ func TestCreateUser_InvalidUsername_Fails(t *testing.T) {
 	// Setup: Create mock repository, service and user
 	ctrl := gomock.NewController(t)
 	mockRepo := mock_repository.NewMockUserRepository(ctrl)
-	userService := NewUserService(mockRepo)
+	userService := NewUserService(mockRepo, 0)
 
 	newUser := &model.User{
 		Username: "jo",
@@ -45,10 +49,10 @@ func TestCreateUser_InvalidUsername_Fails(t *testing.T) {
 		FullName: "John Doe",
 	}
 
-	mockRepo.EXPECT().Create(newUser).Return(newUser, nil).Times(0)
+	mockRepo.EXPECT().Create(gomock.Any(), newUser).Return(newUser, nil).Times(0)
 
 	// When: Calling create from user service
-	createdUser, err := userService.Create(newUser)
+	createdUser, err := userService.Create(context.Background(), newUser)
 
 	// Then: The result should be an ErrInvalidUsername error and nil user
 	assert.ErrorIs(t, err, ErrInvalidUsername, "expected invalid username error")
@@ -60,7 +64,7 @@ func TestCreateUser_InvalidEmail_Fails(t *testing.T) {
 	// Setup: Create mock repository, service and user
 	ctrl := gomock.NewController(t)
 	mockRepo := mock_repository.NewMockUserRepository(ctrl)
-	userService := NewUserService(mockRepo)
+	userService := NewUserService(mockRepo, 0)
 
 	newUser := &model.User{
 		Username: "john_doe",
@@ -68,10 +72,10 @@ func TestCreateUser_InvalidEmail_Fails(t *testing.T) {
 		FullName: "John Doe",
 	}
 
-	mockRepo.EXPECT().Create(newUser).Return(newUser, nil).Times(0)
+	mockRepo.EXPECT().Create(gomock.Any(), newUser).Return(newUser, nil).Times(0)
 
 	// When: Calling create from user service
-	createdUser, err := userService.Create(newUser)
+	createdUser, err := userService.Create(context.Background(), newUser)
 
 	// Then: The result should be an ErrInvalidEmail error and nil user
 	assert.ErrorIs(t, err, ErrInvalidEmail, "expected invalid email error")
@@ -83,7 +87,7 @@ func TestCreateUser_InvalidFullName_Fails(t *testing.T) {
 	// Setup: Create mock repository, service and user
 	ctrl := gomock.NewController(t)
 	mockRepo := mock_repository.NewMockUserRepository(ctrl)
-	userService := NewUserService(mockRepo)
+	userService := NewUserService(mockRepo, 0)
 
 	newUser := &model.User{
 		Username: "john_doe",
@@ -91,10 +95,10 @@ func TestCreateUser_InvalidFullName_Fails(t *testing.T) {
 		FullName: "1John Doe",
 	}
 
-	mockRepo.EXPECT().Create(newUser).Return(newUser, nil).Times(0)
+	mockRepo.EXPECT().Create(gomock.Any(), newUser).Return(newUser, nil).Times(0)
 
 	// When: Calling create from user service
-	createdUser, err := userService.Create(newUser)
+	createdUser, err := userService.Create(context.Background(), newUser)
 
 	// Then: The result should be an ErrInvalidFullName error and nil user
 	assert.ErrorIs(t, err, ErrInvalidFullName, "expected invalid email error")
@@ -106,7 +110,7 @@ func TestUpdateUser_Success(t *testing.T) {
 	// Setup: Create mock repository, service and user
 	ctrl := gomock.NewController(t)
 	mockRepo := mock_repository.NewMockUserRepository(ctrl)
-	userService := NewUserService(mockRepo)
+	userService := NewUserService(mockRepo, 0)
 
 	newUser := &model.User{
 		Username: "john_doe",
@@ -114,10 +118,10 @@ func TestUpdateUser_Success(t *testing.T) {
 		FullName: "John Doe",
 	}
 
-	mockRepo.EXPECT().Update(newUser).Return(newUser, nil).Times(1)
+	mockRepo.EXPECT().Update(gomock.Any(), newUser).Return(newUser, nil).Times(1)
 
 	// When: Calling create from user service
-	createdUser, err := userService.Update(newUser)
+	createdUser, err := userService.Update(context.Background(), newUser)
 
 	// Then: The result should be the created user and no error
 	assert.NoError(t, err, "expected no error")
@@ -129,7 +133,7 @@ func TestUpdateUser_InvalidUsername_Fails(t *testing.T) {
 	// Setup: Create mock repository, service and user
 	ctrl := gomock.NewController(t)
 	mockRepo := mock_repository.NewMockUserRepository(ctrl)
-	userService := NewUserService(mockRepo)
+	userService := NewUserService(mockRepo, 0)
 
 	newUser := &model.User{
 		Username: "jo",
@@ -137,10 +141,10 @@ func TestUpdateUser_InvalidUsername_Fails(t *testing.T) {
 		FullName: "John Doe",
 	}
 
-	mockRepo.EXPECT().Update(newUser).Return(newUser, nil).Times(0)
+	mockRepo.EXPECT().Update(gomock.Any(), newUser).Return(newUser, nil).Times(0)
 
 	// When: Calling create from user service
-	createdUser, err := userService.Update(newUser)
+	createdUser, err := userService.Update(context.Background(), newUser)
 
 	// Then: The result should be an ErrInvalidUsername error and nil user
 	assert.ErrorIs(t, err, ErrInvalidUsername, "expected invalid username error")
@@ -152,7 +156,7 @@ func TestUpdateUser_InvalidEmail_Fails(t *testing.T) {
 	// Setup: Create mock repository, service and user
 	ctrl := gomock.NewController(t)
 	mockRepo := mock_repository.NewMockUserRepository(ctrl)
-	userService := NewUserService(mockRepo)
+	userService := NewUserService(mockRepo, 0)
 
 	newUser := &model.User{
 		Username: "john_doe",
@@ -160,10 +164,10 @@ func TestUpdateUser_InvalidEmail_Fails(t *testing.T) {
 		FullName: "John Doe",
 	}
 
-	mockRepo.EXPECT().Update(newUser).Return(newUser, nil).Times(0)
+	mockRepo.EXPECT().Update(gomock.Any(), newUser).Return(newUser, nil).Times(0)
 
 	// When: Calling create from user service
-	createdUser, err := userService.Update(newUser)
+	createdUser, err := userService.Update(context.Background(), newUser)
 
 	// Then: The result should be an ErrInvalidEmail error and nil user
 	assert.ErrorIs(t, err, ErrInvalidEmail, "expected invalid email error")
@@ -175,7 +179,7 @@ func TestUpdateUser_InvalidFullName_Fails(t *testing.T) {
 	// Setup: Create mock repository, service and user
 	ctrl := gomock.NewController(t)
 	mockRepo := mock_repository.NewMockUserRepository(ctrl)
-	userService := NewUserService(mockRepo)
+	userService := NewUserService(mockRepo, 0)
 
 	newUser := &model.User{
 		Username: "john_doe",
@@ -183,12 +187,539 @@ func TestUpdateUser_InvalidFullName_Fails(t *testing.T) {
 		FullName: "1John Doe",
 	}
 
-	mockRepo.EXPECT().Update(newUser).Return(newUser, nil).Times(0)
+	mockRepo.EXPECT().Update(gomock.Any(), newUser).Return(newUser, nil).Times(0)
 
 	// When: Calling create from user service
-	createdUser, err := userService.Update(newUser)
+	createdUser, err := userService.Update(context.Background(), newUser)
 
 	// Then: The result should be an ErrInvalidFullName error and nil user
 	assert.ErrorIs(t, err, ErrInvalidFullName, "expected invalid full name error")
 	assert.Nil(t, createdUser, "expected no user to be returned")
 }
+
+// Given: More users exist than fit on one page
+func TestGetAll_ReturnsNextCursor(t *testing.T) {
+	// Setup: Create mock repository returning limit+1 users
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 0)
+
+	users := []model.User{{ID: 1}, {ID: 2}, {ID: 3}}
+	expected := repository.ListOptions{Limit: 3, Sort: []repository.SortField{{Field: "id"}}}
+	mockRepo.EXPECT().GetAll(gomock.Any(), expected).Return(users, nil).Times(1)
+
+	// When: Calling get all with limit 2
+	page, err := userService.GetAll(context.Background(), model.UserListParams{Limit: 2})
+
+	// Then: The page should hold two users and a cursor pointing after the second one
+	assert.NoError(t, err, "expected no error")
+	assert.Len(t, page.Users, 2, "expected page to be trimmed to limit")
+	cursor, err := decodeCursor(page.NextCursor)
+	assert.NoError(t, err, "expected next cursor to decode")
+	assert.Equal(t, int64(2), cursor.ID, "expected cursor to point at last user on page")
+}
+
+// Given: The cursor points at the last page
+func TestGetAll_LastPage_NoCursor(t *testing.T) {
+	// Setup: Create mock repository returning fewer users than the limit
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 0)
+
+	cursor := encodeCursor(pageCursor{Sort: "id", ID: 2})
+	expected := repository.ListOptions{
+		Limit: DefaultPageLimit + 1,
+		Sort:  []repository.SortField{{Field: "id"}},
+		After: []any{int64(2)},
+	}
+	mockRepo.EXPECT().GetAll(gomock.Any(), expected).Return(nil, nil).Times(1)
+
+	// When: Calling get all with the cursor and the default limit
+	page, err := userService.GetAll(context.Background(), model.UserListParams{Cursor: cursor})
+
+	// Then: The page should be empty and have no next cursor
+	assert.NoError(t, err, "expected no error")
+	assert.Empty(t, page.Users, "expected no users")
+	assert.NotNil(t, page.Users, "expected an empty list rather than null")
+	assert.Empty(t, page.NextCursor, "expected no next cursor")
+}
+
+// Given: Users are listed with filters and a custom sort
+func TestGetAll_FilterAndSort(t *testing.T) {
+	// Setup: Create mock repository returning a full page sorted by username descending
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 0)
+
+	sort := []repository.SortField{{Field: "username", Desc: true}, {Field: "id"}}
+	first := repository.ListOptions{
+		Limit:  2,
+		Sort:   sort,
+		Filter: repository.UserFilter{UsernamePrefix: "j", EmailDomain: "doe.ee", IncludeDeleted: true},
+	}
+	users := []model.User{{ID: 4, Username: "john"}, {ID: 2, Username: "jane"}}
+	mockRepo.EXPECT().GetAll(gomock.Any(), first).Return(users, nil).Times(1)
+
+	second := first
+	second.After = []any{"john", int64(4)}
+	mockRepo.EXPECT().GetAll(gomock.Any(), second).Return(users[1:], nil).Times(1)
+
+	// When: Calling get all for the first page and then following its cursor
+	params := model.UserListParams{Limit: 1, UsernamePrefix: "j", EmailDomain: "doe.ee", Sort: "-username", IncludeDeleted: true}
+	page, err := userService.GetAll(context.Background(), params)
+	assert.NoError(t, err, "expected no error")
+	params.Cursor = page.NextCursor
+	next, err := userService.GetAll(context.Background(), params)
+
+	// Then: The filter, sort and keyset should be passed to the repository
+	assert.NoError(t, err, "expected no error")
+	assert.Equal(t, "john", page.Users[0].Username, "expected first page to hold john")
+	assert.Equal(t, "jane", next.Users[0].Username, "expected second page to hold jane")
+	assert.Empty(t, next.NextCursor, "expected no cursor after last page")
+}
+
+// Given: A request with a limit, cursor or sort that cannot be used
+func TestGetAll_InvalidParams_Fails(t *testing.T) {
+	// Setup: Create mock repository and service
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 0)
+
+	mockRepo.EXPECT().GetAll(gomock.Any(), gomock.Any()).Times(0)
+	idCursor := encodeCursor(pageCursor{Sort: "id", ID: 2})
+
+	// When: Calling get all with invalid parameters
+	_, limitErr := userService.GetAll(context.Background(), model.UserListParams{Limit: MaxPageLimit + 1})
+	_, cursorErr := userService.GetAll(context.Background(), model.UserListParams{Cursor: "not a cursor!"})
+	_, sortErr := userService.GetAll(context.Background(), model.UserListParams{Sort: "password"})
+	_, mismatchErr := userService.GetAll(context.Background(), model.UserListParams{Sort: "username", Cursor: idCursor})
+
+	// Then: The matching validation errors should be returned
+	assert.ErrorIs(t, limitErr, ErrInvalidLimit, "expected invalid limit error")
+	assert.ErrorIs(t, cursorErr, ErrInvalidCursor, "expected invalid cursor error")
+	assert.ErrorIs(t, sortErr, ErrInvalidSort, "expected invalid sort error")
+	assert.ErrorIs(t, mismatchErr, ErrInvalidCursor, "expected cursor from another sort to be rejected")
+}
+
+// Given: A merge patch that only changes the full name
+func TestPatchUser_MergePatch_Success(t *testing.T) {
+	// Setup: Create mock repository returning the current user
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 0)
+
+	current := &model.User{ID: 1, UUID: "123e4567-e89b-12d3-a456-426614174000", Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe", Version: 4}
+	expected := *current
+	expected.FullName = "Johnny Doe"
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(1), false).Return(current, nil).Times(1)
+	mockRepo.EXPECT().Update(gomock.Any(), &expected).Return(&expected, nil).Times(1)
+
+	// When: Calling patch with a merge patch
+	patched, err := userService.Patch(context.Background(), 1, 0, MergePatch, []byte(`{"full_name":"Johnny Doe"}`))
+
+	// Then: The merged user should be updated and returned
+	assert.NoError(t, err, "expected no error")
+	assert.Equal(t, &expected, patched, "expected only full name to change")
+}
+
+// Given: A JSON Patch operation list that replaces the email
+func TestPatchUser_JSONPatch_Success(t *testing.T) {
+	// Setup: Create mock repository returning the current user
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 0)
+
+	current := &model.User{ID: 1, Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe"}
+	expected := *current
+	expected.Email = "john@example.com"
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(1), false).Return(current, nil).Times(1)
+	mockRepo.EXPECT().Update(gomock.Any(), &expected).Return(&expected, nil).Times(1)
+
+	// When: Calling patch with a test and a replace operation
+	ops := `[{"op":"test","path":"/email","value":"john@doe.ee"},{"op":"replace","path":"/email","value":"john@example.com"}]`
+	patched, err := userService.Patch(context.Background(), 1, 0, JSONPatch, []byte(ops))
+
+	// Then: The patched user should be updated and returned
+	assert.NoError(t, err, "expected no error")
+	assert.Equal(t, &expected, patched, "expected only email to change")
+}
+
+// Given: Patches that cannot be applied to the user
+func TestPatchUser_InvalidPatches_Fail(t *testing.T) {
+	// Setup: Create mock repository returning the current user for every patch
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 0)
+
+	current := &model.User{ID: 1, Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe"}
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(1), false).DoAndReturn(func(context.Context, int64, bool) (*model.User, error) {
+		u := *current
+		return &u, nil
+	}).Times(5)
+	mockRepo.EXPECT().Update(gomock.Any(), gomock.Any()).Times(0)
+
+	// When: Calling patch with broken, failing, identifier changing and invalid patches
+	_, badErr := userService.Patch(context.Background(), 1, 0, MergePatch, []byte(`{bad`))
+	_, testErr := userService.Patch(context.Background(), 1, 0, JSONPatch, []byte(`[{"op":"test","path":"/username","value":"jane"}]`))
+	_, idErr := userService.Patch(context.Background(), 1, 0, MergePatch, []byte(`{"id":2}`))
+	_, typeErr := userService.Patch(context.Background(), 1, 0, MergePatch, []byte(`{"username":5}`))
+	_, validationErr := userService.Patch(context.Background(), 1, 0, MergePatch, []byte(`{"username":null}`))
+
+	// Then: The matching errors should be returned and nothing updated
+	assert.ErrorIs(t, badErr, ErrInvalidPatch, "expected invalid patch error")
+	assert.ErrorIs(t, testErr, ErrPatchTestFailed, "expected patch test failed error")
+	assert.ErrorIs(t, idErr, ErrImmutableIdentifier, "expected immutable identifier error")
+	assert.ErrorIs(t, typeErr, ErrInvalidPatch, "expected invalid patch error")
+	assert.ErrorIs(t, validationErr, ErrInvalidUsername, "expected merged user to be validated")
+}
+
+// Given: A user whose version has moved on since the client read it
+func TestUpdateUser_StaleVersion_Fails(t *testing.T) {
+	// Setup: Create mock repository reporting a version conflict
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 0)
+
+	user := &model.User{ID: 1, Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe", Version: 2}
+	mockRepo.EXPECT().Update(gomock.Any(), user).Return(nil, repository.ErrVersionConflict).Times(1)
+	mockRepo.EXPECT().Delete(gomock.Any(), int64(1), int64(2)).Return(repository.ErrVersionConflict).Times(1)
+
+	// When: Calling update and delete with the stale version
+	updated, updateErr := userService.Update(context.Background(), user)
+	deleteErr := userService.Delete(context.Background(), 1, 2)
+
+	// Then: The result should be ErrPreconditionFailed
+	assert.ErrorIs(t, updateErr, ErrPreconditionFailed, "expected precondition failed error")
+	assert.Nil(t, updated, "expected no user to be returned")
+	assert.ErrorIs(t, deleteErr, ErrPreconditionFailed, "expected precondition failed error")
+}
+
+// Given: A patch sent with an If-Match version that is no longer current
+func TestPatchUser_StaleVersion_Fails(t *testing.T) {
+	// Setup: Create mock repository returning the user at version 3
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 0)
+
+	current := &model.User{ID: 1, Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe", Version: 3}
+	mockRepo.EXPECT().GetByID(gomock.Any(), int64(1), false).Return(current, nil).Times(1)
+	mockRepo.EXPECT().Update(gomock.Any(), gomock.Any()).Times(0)
+
+	// When: Calling patch expecting version 2
+	patched, err := userService.Patch(context.Background(), 1, 2, MergePatch, []byte(`{"full_name":"Johnny Doe"}`))
+
+	// Then: The result should be ErrPreconditionFailed and nothing updated
+	assert.ErrorIs(t, err, ErrPreconditionFailed, "expected precondition failed error")
+	assert.Nil(t, patched, "expected no user to be returned")
+}
+
+// Given: A service with a retention period for deleted users
+func TestPurge_UsesRetention(t *testing.T) {
+	// Setup: Create mock repository and service keeping deleted users for a day
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 24*time.Hour)
+
+	var cutoff time.Time
+	mockRepo.EXPECT().Purge(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, deletedBefore time.Time) (int64, error) {
+		cutoff = deletedBefore
+		return 2, nil
+	}).Times(1)
+
+	// When: Calling purge
+	purged, err := userService.Purge(context.Background())
+
+	// Then: Users deleted more than a day ago should be purged
+	assert.NoError(t, err, "expected no error")
+	assert.Equal(t, int64(2), purged, "expected purged count from repository")
+	assert.WithinDuration(t, time.Now().Add(-24*time.Hour), cutoff, time.Minute, "expected cutoff one retention period ago")
+}
+
+// Given: A service without a retention period
+func TestPurge_Disabled_Fails(t *testing.T) {
+	// Setup: Create mock repository and service with no retention
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 0)
+
+	mockRepo.EXPECT().Purge(gomock.Any(), gomock.Any()).Times(0)
+
+	// When: Calling purge
+	_, err := userService.Purge(context.Background())
+
+	// Then: The result should be ErrPurgeDisabled
+	assert.ErrorIs(t, err, ErrPurgeDisabled, "expected purge disabled error")
+}
+
+// Given: A user that is not deleted
+func TestRestore_NotDeleted_Fails(t *testing.T) {
+	// Setup: Create mock repository reporting the user is not deleted
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 0)
+
+	mockRepo.EXPECT().Restore(gomock.Any(), int64(1)).Return(nil, repository.ErrNotDeleted).Times(1)
+
+	// When: Calling restore
+	restored, err := userService.Restore(context.Background(), 1)
+
+	// Then: The result should be ErrUserNotDeleted
+	assert.ErrorIs(t, err, ErrUserNotDeleted, "expected user not deleted error")
+	assert.Nil(t, restored, "expected no user to be returned")
+}
+
+// Given: A deleted user whose email was reused by another user
+func TestRestore_EmailReused_Fails(t *testing.T) {
+	// Setup: Create mock repository reporting the email conflict
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 0)
+
+	mockRepo.EXPECT().Restore(gomock.Any(), int64(1)).Return(nil, &repository.UniqueConstraintError{Field: "email"}).Times(1)
+
+	// When: Calling restore
+	restored, err := userService.Restore(context.Background(), 1)
+
+	// Then: The result should be ErrEmailAlreadyExists
+	assert.ErrorIs(t, err, ErrEmailAlreadyExists, "expected email already exists error")
+	assert.Nil(t, restored, "expected no user to be returned")
+}
+
+// Given: Users are listed by update time for an incremental sync
+func TestGetAll_UpdatedAfterSortedByUpdatedAt(t *testing.T) {
+	// Setup: Create mock repository returning a full page sorted by updated_at
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 0)
+
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	updated := since.Add(1500 * time.Microsecond)
+	sort := []repository.SortField{{Field: "updated_at"}, {Field: "id"}}
+	first := repository.ListOptions{Limit: 2, Sort: sort, Filter: repository.UserFilter{UpdatedAfter: since}}
+	users := []model.User{{ID: 3, UpdatedAt: updated}, {ID: 1, UpdatedAt: updated.Add(time.Second)}}
+	mockRepo.EXPECT().GetAll(gomock.Any(), first).Return(users, nil).Times(1)
+
+	second := first
+	second.After = []any{"2025-01-01T00:00:00.0015Z", int64(3)}
+	mockRepo.EXPECT().GetAll(gomock.Any(), second).Return(users[1:], nil).Times(1)
+
+	// When: Calling get all for the first page and then following its cursor
+	params := model.UserListParams{Limit: 1, UpdatedAfter: since, Sort: "updated_at"}
+	page, err := userService.GetAll(context.Background(), params)
+	assert.NoError(t, err, "expected no error")
+	params.Cursor = page.NextCursor
+	_, err = userService.GetAll(context.Background(), params)
+
+	// Then: The cursor should carry the update time at full precision
+	assert.NoError(t, err, "expected no error")
+}
+
+// Given: A best-effort bulk create with an invalid and a conflicting user
+func TestCreateBulk_BestEffort(t *testing.T) {
+	// Setup: Create mock repository rejecting the second valid user's email
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 0)
+
+	users := []model.User{
+		{Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe"},
+		{Username: "Invalid", Email: "invalid@doe.ee", FullName: "Invalid User"},
+		{Username: "jane_doe", Email: "john@doe.ee", FullName: "Jane Doe"},
+	}
+	mockRepo.EXPECT().CreateMany(gomock.Any(), []*model.User{&users[0], &users[2]}, false).
+		Return([]error{nil, &repository.UniqueConstraintError{Field: "email"}}, nil).Times(1)
+
+	// When: Calling create bulk in best-effort mode
+	results, err := userService.CreateBulk(context.Background(), users, false)
+
+	// Then: Each user should get its own outcome
+	assert.NoError(t, err, "expected no error")
+	assert.NoError(t, results[0].Err, "expected first user to be created")
+	assert.Equal(t, "john_doe", results[0].User.Username, "expected created user")
+	assert.ErrorIs(t, results[1].Err, ErrInvalidUsername, "expected invalid username error")
+	assert.ErrorIs(t, results[2].Err, ErrEmailAlreadyExists, "expected email already exists error")
+}
+
+// Given: An atomic bulk create with an invalid user
+func TestCreateBulk_Atomic_InvalidUser_CreatesNothing(t *testing.T) {
+	// Setup: Create mock repository that must not be called
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 0)
+
+	users := []model.User{
+		{Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe"},
+		{Username: "jane_doe", Email: "invalid", FullName: "Jane Doe"},
+	}
+	mockRepo.EXPECT().CreateMany(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	// When: Calling create bulk in atomic mode
+	results, err := userService.CreateBulk(context.Background(), users, true)
+
+	// Then: The valid user should be aborted and the invalid one rejected
+	assert.NoError(t, err, "expected no error")
+	assert.ErrorIs(t, results[0].Err, ErrBulkAborted, "expected aborted error")
+	assert.ErrorIs(t, results[1].Err, ErrInvalidEmail, "expected invalid email error")
+}
+
+// Given: An atomic bulk create rolled back by a conflict
+func TestCreateBulk_Atomic_Conflict_RollsBack(t *testing.T) {
+	// Setup: Create mock repository rolling back on a duplicate username
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 0)
+
+	users := []model.User{
+		{Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe"},
+		{Username: "jane_doe", Email: "jane@doe.ee", FullName: "Jane Doe"},
+	}
+	mockRepo.EXPECT().CreateMany(gomock.Any(), gomock.Len(2), true).
+		Return([]error{repository.ErrRolledBack, &repository.UniqueConstraintError{Field: "username"}}, nil).Times(1)
+
+	// When: Calling create bulk in atomic mode
+	results, err := userService.CreateBulk(context.Background(), users, true)
+
+	// Then: No user should be reported as created
+	assert.NoError(t, err, "expected no error")
+	assert.ErrorIs(t, results[0].Err, ErrBulkAborted, "expected aborted error")
+	assert.Nil(t, results[0].User, "expected no created user")
+	assert.ErrorIs(t, results[1].Err, ErrUsernameAlreadyExists, "expected username already exists error")
+}
+
+// Given: A bulk create without users
+func TestCreateBulk_Empty_Fails(t *testing.T) {
+	// Setup: Create mock repository and service
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 0)
+
+	// When: Calling create bulk with no users
+	_, err := userService.CreateBulk(context.Background(), nil, false)
+
+	// Then: The result should be ErrInvalidBulkSize
+	assert.ErrorIs(t, err, ErrInvalidBulkSize, "expected invalid bulk size error")
+}
+
+// Given: Users are exported with a filter and a page limit
+func TestExport_IgnoresPaging(t *testing.T) {
+	// Setup: Create mock repository expecting an unlimited filtered stream
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 0)
+
+	expected := repository.ListOptions{
+		Sort:   []repository.SortField{{Field: "username"}, {Field: "id"}},
+		Filter: repository.UserFilter{EmailDomain: "doe.ee"},
+	}
+	mockRepo.EXPECT().Stream(gomock.Any(), expected, gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ repository.ListOptions, fn func(*model.User) error) error {
+			return fn(&model.User{ID: 1, Username: "john_doe"})
+		}).Times(1)
+
+	// When: Calling export with a limit and cursor
+	var exported []string
+	err := userService.Export(context.Background(), model.UserListParams{Limit: 1, Cursor: "next", EmailDomain: "doe.ee", Sort: "username"},
+		func(u *model.User) error {
+			exported = append(exported, u.Username)
+			return nil
+		})
+
+	// Then: Every streamed user should be passed on
+	assert.NoError(t, err, "expected no error")
+	assert.Equal(t, []string{"john_doe"}, exported, "expected streamed users")
+}
+
+// Given: An import with an invalid, a taken and a duplicated user
+func TestImport_DryRun_ReportsEveryRow(t *testing.T) {
+	// Setup: Create mock repository reporting john_doe as taken
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 0)
+
+	users := []model.User{
+		{Username: "john_doe", Email: "john@doe.ee", FullName: "John Doe"},
+		{Username: "jane_doe", Email: "jane@doe.ee", FullName: "Jane Doe"},
+		{Username: "jane_roe", Email: "jane@doe.ee", FullName: "Jane Roe"},
+		{Username: "x", Email: "x@doe.ee", FullName: "X Doe"},
+	}
+	mockRepo.EXPECT().Taken(gomock.Any(),
+		[]string{"john_doe", "jane_doe", "jane_roe"},
+		[]string{"john@doe.ee", "jane@doe.ee", "jane@doe.ee"}).
+		Return([]string{"john_doe"}, nil, nil).Times(1)
+	mockRepo.EXPECT().CreateMany(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	// When: Calling import as a dry run
+	results, err := userService.Import(context.Background(), users, true)
+
+	// Then: Only jane_doe should pass and nothing should be written
+	assert.NoError(t, err, "expected no error")
+	assert.ErrorIs(t, results[0].Err, ErrUsernameAlreadyExists, "expected taken username")
+	assert.NoError(t, results[1].Err, "expected jane_doe to pass")
+	assert.Equal(t, "jane_doe", results[1].User.Username, "expected user that would be created")
+	assert.ErrorIs(t, results[2].Err, ErrEmailAlreadyExists, "expected duplicate email within import")
+	assert.ErrorIs(t, results[3].Err, ErrInvalidUsername, "expected invalid username")
+}
+
+// Given: An import with a valid and an invalid user
+func TestImport_Commit_CreatesValidRows(t *testing.T) {
+	// Setup: Create mock repository creating the valid user in a transaction
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 0)
+
+	users := []model.User{
+		{Username: "john_doe", Email: "invalid", FullName: "John Doe"},
+		{Username: "jane_doe", Email: "jane@doe.ee", FullName: "Jane Doe"},
+	}
+	mockRepo.EXPECT().Taken(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil, nil).Times(1)
+	mockRepo.EXPECT().CreateMany(gomock.Any(), []*model.User{&users[1]}, true).
+		DoAndReturn(func(_ context.Context, created []*model.User, _ bool) ([]error, error) {
+			created[0].ID = 2
+			return []error{nil}, nil
+		}).Times(1)
+
+	// When: Calling import
+	results, err := userService.Import(context.Background(), users, false)
+
+	// Then: The valid user should be created and the invalid one reported
+	assert.NoError(t, err, "expected no error")
+	assert.ErrorIs(t, results[0].Err, ErrInvalidEmail, "expected invalid email")
+	assert.Equal(t, int64(2), results[1].User.ID, "expected created user")
+}
+
+// Given: A search query surrounded by spaces
+func TestSearch_TrimsQueryAndDefaultsLimit(t *testing.T) {
+	// Setup: Create mock repository returning no matches
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 0)
+
+	mockRepo.EXPECT().Search(gomock.Any(), "john", DefaultSearchLimit).Return(nil, nil).Times(1)
+
+	// When: Calling search
+	result, err := userService.Search(context.Background(), model.UserSearchParams{Query: "  john "})
+
+	// Then: The trimmed query and default limit should be used
+	assert.NoError(t, err, "expected no error")
+	assert.NotNil(t, result.Users, "expected an empty list rather than null")
+}
+
+// Given: Invalid search parameters
+func TestSearch_InvalidParams_Fail(t *testing.T) {
+	// Setup: Create mock repository that must not be called
+	ctrl := gomock.NewController(t)
+	mockRepo := mock_repository.NewMockUserRepository(ctrl)
+	userService := NewUserService(mockRepo, 0)
+
+	mockRepo.EXPECT().Search(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	// When: Calling search with a blank query, a too long query and a too large limit
+	_, blankErr := userService.Search(context.Background(), model.UserSearchParams{Query: "  "})
+	_, longErr := userService.Search(context.Background(), model.UserSearchParams{Query: strings.Repeat("a", MaxSearchQueryLength+1)})
+	_, limitErr := userService.Search(context.Background(), model.UserSearchParams{Query: "john", Limit: MaxPageLimit + 1})
+
+	// Then: The matching validation errors should be returned
+	assert.ErrorIs(t, blankErr, ErrInvalidSearchQuery, "expected invalid search query error")
+	assert.ErrorIs(t, longErr, ErrInvalidSearchQuery, "expected invalid search query error")
+	assert.ErrorIs(t, limitErr, ErrInvalidLimit, "expected invalid limit error")
+}
@@ -0,0 +1,187 @@
+package tracing
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// rowsAffectedKey holds the rows an Exec changed, which the semantic
+// conventions have no attribute for.
+const rowsAffectedKey = attribute.Key("db.response.affected_rows")
+
+// WrapConnector starts a span for every statement and transaction run on the
+// connections of c. Spans hold the statement with its placeholders, never the
+// arguments, and the number of rows returned or affected. Drivers that only
+// support prepared statements are not traced; lib/pq runs queries directly.
+func WrapConnector(c driver.Connector, tp trace.TracerProvider, system attribute.KeyValue) driver.Connector {
+	return &connector{next: c, tracer: tp.Tracer("cruder/internal/tracing"), system: system}
+}
+
+type connector struct {
+	next   driver.Connector
+	tracer trace.Tracer
+	system attribute.KeyValue
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.next.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn, connector: c}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return c.next.Driver()
+}
+
+// start starts a client span named after the operation of query.
+func (c *connector) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	operation = strings.ToUpper(operation)
+	return c.tracer.Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(c.system, semconv.DBOperationName(operation), semconv.DBQueryText(query)),
+	)
+}
+
+type tracedConn struct {
+	driver.Conn
+	connector *connector
+}
+
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (_ driver.Rows, err error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, span := c.connector.start(ctx, query)
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != nil {
+		End(span, &err)
+		return nil, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (_ driver.Result, err error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	ctx, span := c.connector.start(ctx, query)
+	defer End(span, &err)
+	res, err := execer.ExecContext(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err == nil {
+		span.SetAttributes(rowsAffectedKey.Int64(n))
+	}
+	return res, nil
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (_ driver.Tx, err error) {
+	beginner, ok := c.Conn.(driver.ConnBeginTx)
+	if !ok {
+		return nil, errors.New("driver does not support BeginTx")
+	}
+	spanCtx, span := c.connector.start(ctx, "BEGIN")
+	defer End(span, &err)
+	tx, err := beginner.BeginTx(spanCtx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedTx{Tx: tx, ctx: ctx, connector: c.connector}, nil
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *tracedConn) CheckNamedValue(v *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(v)
+	}
+	return driver.ErrSkip
+}
+
+// tracedTx traces the end of a transaction in the context it was begun in.
+type tracedTx struct {
+	driver.Tx
+	ctx       context.Context
+	connector *connector
+}
+
+func (tx *tracedTx) Commit() (err error) {
+	_, span := tx.connector.start(tx.ctx, "COMMIT")
+	defer End(span, &err)
+	return tx.Tx.Commit()
+}
+
+func (tx *tracedTx) Rollback() (err error) {
+	_, span := tx.connector.start(tx.ctx, "ROLLBACK")
+	defer End(span, &err)
+	return tx.Tx.Rollback()
+}
+
+// tracedRows ends the span of its query once closed, which database/sql does
+// when the rows are exhausted or the query is abandoned.
+type tracedRows struct {
+	driver.Rows
+	span trace.Span
+	n    int
+	err  error
+}
+
+func (r *tracedRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	switch {
+	case err == nil:
+		r.n++
+	case err != io.EOF:
+		r.err = err
+	}
+	return err
+}
+
+func (r *tracedRows) Close() error {
+	err := r.Rows.Close()
+	if r.err == nil {
+		r.err = err
+	}
+	r.span.SetAttributes(semconv.DBResponseReturnedRows(r.n))
+	End(r.span, &r.err)
+	return err
+}
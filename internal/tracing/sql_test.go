@@ -0,0 +1,140 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// dsnConnector opens connections to the sqlmock database registered under dsn.
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// helper to open a traced mock database whose spans end up in the recorder
+func newTracedDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock, *tracetest.SpanRecorder, *sdktrace.TracerProvider) {
+	mockDB, mock, err := sqlmock.NewWithDSN(t.Name())
+	assert.NoError(t, err)
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	db := sql.OpenDB(WrapConnector(dsnConnector{t.Name(), mockDB.Driver()}, tp, semconv.DBSystemNamePostgreSQL))
+	t.Cleanup(func() {
+		_ = db.Close()
+		_ = mockDB.Close()
+	})
+	return db, mock, recorder, tp
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestWrapConnector_Query(t *testing.T) {
+	// Given: a traced database returning two rows
+	db, mock, recorder, _ := newTracedDB(t)
+	mock.ExpectQuery(`SELECT id FROM users`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+
+	// When: the rows are read
+	rows, err := db.QueryContext(context.Background(), `SELECT id FROM users WHERE deleted_at IS NULL`)
+	assert.NoError(t, err)
+	for rows.Next() {
+	}
+	assert.NoError(t, rows.Close())
+
+	// Then: one span should hold the statement and the number of rows
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "SELECT", spans[0].Name())
+	attrs := attributes(spans[0])
+	assert.Equal(t, "postgresql", attrs[semconv.DBSystemNameKey].AsString())
+	assert.Equal(t, `SELECT id FROM users WHERE deleted_at IS NULL`, attrs[semconv.DBQueryTextKey].AsString())
+	assert.Equal(t, int64(2), attrs[semconv.DBResponseReturnedRowsKey].AsInt64())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+}
+
+func TestWrapConnector_Exec(t *testing.T) {
+	// Given: a traced database where a delete affects three rows
+	db, mock, recorder, _ := newTracedDB(t)
+	mock.ExpectExec(`DELETE FROM users`).WithArgs(42).WillReturnResult(sqlmock.NewResult(0, 3))
+
+	// When: the statement is run
+	_, err := db.ExecContext(context.Background(), `DELETE FROM users WHERE id = $1`, 42)
+
+	// Then: the span should hold the statement without its arguments and
+	// the rows affected
+	assert.NoError(t, err)
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "DELETE", spans[0].Name())
+	attrs := attributes(spans[0])
+	assert.Equal(t, `DELETE FROM users WHERE id = $1`, attrs[semconv.DBQueryTextKey].AsString())
+	assert.Equal(t, int64(3), attrs[rowsAffectedKey].AsInt64())
+}
+
+func TestWrapConnector_Error(t *testing.T) {
+	// Given: a traced database whose query fails
+	db, mock, recorder, _ := newTracedDB(t)
+	mock.ExpectQuery(`SELECT`).WillReturnError(errors.New("connection reset"))
+
+	// When: the query is run
+	err := db.QueryRowContext(context.Background(), `SELECT 1`).Scan(new(int))
+
+	// Then: the span should be marked as failed
+	assert.Error(t, err)
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "connection reset", spans[0].Status().Description)
+}
+
+func TestWrapConnector_Transaction(t *testing.T) {
+	// Given: a traced database and a parent span
+	db, mock, recorder, tp := newTracedDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE users`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+
+	// When: a statement is run in a transaction
+	tx, err := db.BeginTx(ctx, nil)
+	assert.NoError(t, err)
+	_, err = tx.ExecContext(ctx, `UPDATE users SET version = version + 1`)
+	assert.NoError(t, err)
+	assert.NoError(t, tx.Commit())
+	parent.End()
+
+	// Then: the transaction and its statement should be children of the
+	// parent span
+	spans := recorder.Ended()
+	var names []string
+	for _, span := range spans[:len(spans)-1] {
+		names = append(names, span.Name())
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	}
+	assert.Equal(t, []string{"BEGIN", "UPDATE", "COMMIT"}, names)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,104 @@
+// Package tracing sets up OpenTelemetry tracing.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// ServiceName is the service.name spans are exported under.
+const ServiceName = "cruder"
+
+// The exporters spans can be sent to.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Propagator reads and writes the W3C traceparent, tracestate and baggage
+// headers.
+var Propagator propagation.TextMapPropagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+type Options struct {
+	// Exporter is none, stdout or otlp. Empty means none.
+	Exporter string
+	// Endpoint is the URL of the OTLP/HTTP traces endpoint. When empty the
+	// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT and OTEL_EXPORTER_OTLP_ENDPOINT
+	// environment variables apply.
+	Endpoint string
+	// SampleRatio is the fraction of new traces that are recorded, from 0
+	// for none to 1 for all of them. Traces continued from a traceparent
+	// header follow the sampling decision of the caller.
+	SampleRatio float64
+	// Writer receives the spans of the stdout exporter, os.Stdout by default.
+	Writer io.Writer
+}
+
+// NewTracerProvider creates the tracer provider for opts. The returned
+// function flushes pending spans and stops the exporter. Without an exporter
+// spans are still propagated but never recorded.
+func NewTracerProvider(ctx context.Context, opts Options) (trace.TracerProvider, func(context.Context) error, error) {
+	if opts.SampleRatio < 0 || opts.SampleRatio > 1 {
+		return nil, nil, fmt.Errorf("tracing sample ratio must be between 0 and 1, got %v", opts.SampleRatio)
+	}
+
+	var export sdktrace.TracerProviderOption
+	switch opts.Exporter {
+	case "", ExporterNone:
+		return noop.NewTracerProvider(), func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		w := opts.Writer
+		if w == nil {
+			w = os.Stdout
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(w))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create stdout exporter: %w", err)
+		}
+		// Writing spans out as they end keeps them next to the logs of the
+		// same request.
+		export = sdktrace.WithSyncer(exporter)
+	case ExporterOTLP:
+		var otlpOpts []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			otlpOpts = append(otlpOpts, otlptracehttp.WithEndpointURL(opts.Endpoint))
+		}
+		exporter, err := otlptracehttp.New(ctx, otlpOpts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		export = sdktrace.WithBatcher(exporter)
+	default:
+		return nil, nil, fmt.Errorf("unknown tracing exporter %q, expected none, stdout or otlp", opts.Exporter)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		export,
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(ServiceName))),
+	)
+	return tp, tp.Shutdown, nil
+}
+
+// End ends span, marking it as failed when *err is set. It is meant to be
+// deferred.
+func End(span trace.Span, err *error) {
+	if *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
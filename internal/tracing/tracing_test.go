@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNewTracerProvider_UnknownExporter(t *testing.T) {
+	// Given: an exporter that does not exist
+	opts := Options{Exporter: "zipkin"}
+
+	// When: the tracer provider is created
+	_, _, err := NewTracerProvider(context.Background(), opts)
+
+	// Then: it should fail naming the exporter
+	assert.ErrorContains(t, err, `unknown tracing exporter "zipkin"`)
+}
+
+func TestNewTracerProvider_InvalidSampleRatio(t *testing.T) {
+	// Given: a sample ratio above one
+	opts := Options{Exporter: ExporterStdout, SampleRatio: 1.5}
+
+	// When: the tracer provider is created
+	_, _, err := NewTracerProvider(context.Background(), opts)
+
+	// Then: it should fail
+	assert.ErrorContains(t, err, "sample ratio")
+}
+
+func TestNewTracerProvider_None(t *testing.T) {
+	// Given: no exporter
+	tp, shutdown, err := NewTracerProvider(context.Background(), Options{})
+	assert.NoError(t, err)
+
+	// When: a span is started
+	_, span := tp.Tracer("test").Start(context.Background(), "work")
+
+	// Then: it should not be recorded
+	assert.False(t, span.IsRecording())
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestNewTracerProvider_Stdout(t *testing.T) {
+	// Given: the stdout exporter writing to a buffer
+	var buf bytes.Buffer
+	tp, shutdown, err := NewTracerProvider(context.Background(), Options{Exporter: ExporterStdout, SampleRatio: 1, Writer: &buf})
+	assert.NoError(t, err)
+
+	// When: a span ends
+	_, span := tp.Tracer("test").Start(context.Background(), "work")
+	span.End()
+	assert.NoError(t, shutdown(context.Background()))
+
+	// Then: it should be written out with the service name
+	assert.Contains(t, buf.String(), `"Name":"work"`)
+	assert.Contains(t, buf.String(), `"Value":"cruder"`)
+}
+
+func TestNewTracerProvider_ZeroSampleRatio(t *testing.T) {
+	// Given: the stdout exporter sampling no new traces
+	var buf bytes.Buffer
+	tp, shutdown, err := NewTracerProvider(context.Background(), Options{Exporter: ExporterStdout, SampleRatio: 0, Writer: &buf})
+	assert.NoError(t, err)
+
+	// When: a new trace is started
+	_, span := tp.Tracer("test").Start(context.Background(), "work")
+	span.End()
+	assert.NoError(t, shutdown(context.Background()))
+
+	// Then: it should not be recorded
+	assert.False(t, span.SpanContext().IsSampled())
+	assert.Empty(t, buf.String())
+}
+
+func TestPropagator_ContinuesTraceparent(t *testing.T) {
+	// Given: a traced router sampling no new traces and a request carrying a
+	// sampled traceparent
+	gin.SetMode(gin.TestMode)
+	var buf bytes.Buffer
+	tp, _, err := NewTracerProvider(context.Background(), Options{Exporter: ExporterStdout, Writer: &buf})
+	assert.NoError(t, err)
+	r := gin.New()
+	r.Use(otelgin.Middleware(ServiceName, otelgin.WithTracerProvider(tp), otelgin.WithPropagators(Propagator)))
+	var got trace.SpanContext
+	r.GET("/users", func(c *gin.Context) {
+		got = trace.SpanContextFromContext(c.Request.Context())
+	})
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	// When: the request is served
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Then: the request span should continue the caller's trace
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", got.TraceID().String())
+	assert.NotEqual(t, "00f067aa0ba902b7", got.SpanID().String())
+	assert.True(t, got.IsSampled())
+	assert.Contains(t, buf.String(), `"Name":"GET /users"`)
+}
@@ -0,0 +1,11 @@
+// Package version holds the build information injected at link time:
+//
+//	go build -ldflags "-X cruder/internal/version.Version=v1.2.0 -X cruder/internal/version.Commit=$(git rev-parse HEAD)"
+package version
+
+var (
+	// Version is the released version of the build.
+	Version = "dev"
+	// Commit is the git commit the build was made from.
+	Commit = "unknown"
+)
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS user_audit (
+    id BIGSERIAL PRIMARY KEY,
+    -- No foreign key, the history of a user outlives the purge of the user.
+    user_id INTEGER NOT NULL,
+    user_uuid UUID NOT NULL,
+    actor TEXT NOT NULL,
+    action TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    before JSONB,
+    after JSONB
+);
+
+CREATE INDEX user_audit_user_id_idx ON user_audit (user_id, id);
+CREATE INDEX user_audit_user_uuid_idx ON user_audit (user_uuid, id);
+CREATE INDEX user_audit_created_at_idx ON user_audit (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_audit;
+-- +goose StatementEnd